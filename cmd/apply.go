@@ -1,7 +1,13 @@
 package cmd
 
 import (
+	"context"
+
 	"github.com/massdriver-cloud/fogmachine/pkg/apply"
+	"github.com/massdriver-cloud/fogmachine/pkg/client"
+	"github.com/massdriver-cloud/fogmachine/pkg/exitcode"
+	"github.com/massdriver-cloud/fogmachine/pkg/outputs"
+	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 )
 
@@ -10,19 +16,140 @@ func ApplyCmd() *cobra.Command {
 		Use:   "apply",
 		Short: "Create or update a Cloudformation stack",
 		Long:  "Create or update a Cloudformation stack",
-		Run:   apply.CfApply,
+		Run:   runApply,
 	}
 
 	cmd.Flags().StringP("package-name", "p", "", "Package name")
 	_ = cmd.MarkFlagRequired("package-name")
 	cmd.Flags().StringP("region", "r", "", "AWS region")
 	_ = cmd.MarkFlagRequired("region")
-	cmd.Flags().StringP("template-path", "", "", "Path to CloudFormation template")
-	_ = cmd.MarkFlagRequired("template-path")
-	cmd.Flags().StringP("parameter-path", "", "", "Path to CloudFormation input vars")
-	_ = cmd.MarkFlagRequired("parameter-path")
+	cmd.Flags().StringP("template-path", "", "", "Path to CloudFormation template, required unless --plan-file is set")
+	cmd.Flags().String("plan-file", "", "Execute the changeset recorded by plan --plan-file instead of creating a new one")
+	cmd.Flags().String("outputs-file", "", "Write the stack outputs to this file after a successful apply")
+	cmd.Flags().String("outputs-format", outputs.FormatJSON, "Format of the outputs file [json, yaml, dotenv, github]")
+	cmd.Flags().Bool("outputs-include-export-names", false, "Include the export name of each output in the outputs file")
+	cmd.Flags().Bool("outputs-include-descriptions", false, "Include the description of each output in the outputs file")
+	cmd.Flags().Bool("detailed-exitcode", false, "Exit with code 2 instead of 0 when the changeset contains no changes")
+	cmd.Flags().Bool("delete-empty-changeset", false, "Delete the changeset when it contains no changes instead of leaving it on the stack")
+	cmd.Flags().Bool("recreate-failed", false, "Delete and recreate a stack that was never created successfully, such as one in ROLLBACK_COMPLETE")
+	cmd.Flags().Bool("continue-rollback", false, "Continue the rollback of a stack in UPDATE_ROLLBACK_FAILED before updating it")
+	cmd.Flags().StringArray("skip-resource", nil, "Logical ID of a resource --continue-rollback skips rolling back, can be repeated")
+	cmd.Flags().Int("prune-keep", -1, "After applying, delete all but this many of the newest changesets fogmachine created, -1 keeps them all")
+	cmd.Flags().Bool("prune-failed", false, "After applying, delete the FAILED changesets fogmachine created")
+	cmd.Flags().Duration("prune-grace-period", client.DefaultPruneGracePeriod, "When pruning, keep changesets that can still be executed, such as pending plans, until they're this old")
+	addParameterFlags(cmd)
+	addStackConfigFlags(cmd)
 	cmd.Flags().Int("timeout", 600, "time in seconds to wait for resources to finish, this does not cancel the cloud formation run")
 	cmd.Flags().Int("poll-interval", 3, "time in seconds between each poll of the AWS api for updates")
+	addBusyFlags(cmd)
+	addEventFlags(cmd)
 
 	return cmd
 }
+
+func runApply(cmd *cobra.Command, _ []string) {
+	// The outputs options are checked before anything is deployed
+	outputsFile, err := cmd.Flags().GetString("outputs-file")
+	if err != nil {
+		log.Fatal().Err(err).Msg("")
+	}
+
+	outputOpts, err := outputOptionsFromFlags(cmd, "outputs-format", "outputs-include-export-names", "outputs-include-descriptions")
+	if err != nil {
+		log.Fatal().Err(err).Msg("")
+	}
+
+	detailedExitCode, err := cmd.Flags().GetBool("detailed-exitcode")
+	if err != nil {
+		log.Fatal().Err(err).Msg("")
+	}
+
+	opts, err := applyOptionsFromFlags(cmd)
+	if err != nil {
+		log.Fatal().Err(err).Msg("")
+	}
+
+	var result *apply.Result
+	err = withLock(cmd, opts.Config, func() error {
+		var applyErr error
+		result, applyErr = apply.Apply(context.Background(), opts)
+		return applyErr
+	})
+	closeEvents(opts.Events)
+
+	if err == nil && outputsFile != "" {
+		err = outputs.WriteTo(outputsFile, result.Outputs, outputOpts)
+	}
+
+	if err == nil && result.NoChanges && detailedExitCode {
+		err = client.ErrNoChanges
+	}
+
+	exitcode.Exit(err)
+}
+
+func applyOptionsFromFlags(cmd *cobra.Command) (apply.Options, error) {
+	opts := apply.Options{}
+
+	var err error
+	if opts.PlanFile, err = cmd.Flags().GetString("plan-file"); err != nil {
+		return opts, err
+	}
+
+	if opts.Template, err = templateInputFromFlags(cmd); err != nil {
+		return opts, err
+	}
+
+	if opts.Stack, err = stackOptionsFromFlags(cmd); err != nil {
+		return opts, err
+	}
+
+	if opts.EffectiveParameters, err = effectiveParametersFromFlags(cmd); err != nil {
+		return opts, err
+	}
+
+	if opts.Config, err = clientConfigFromFlags(cmd); err != nil {
+		return opts, err
+	}
+
+	if opts.DeleteEmptyChangeset, err = cmd.Flags().GetBool("delete-empty-changeset"); err != nil {
+		return opts, err
+	}
+
+	if opts.RecreateFailed, err = cmd.Flags().GetBool("recreate-failed"); err != nil {
+		return opts, err
+	}
+
+	if opts.ContinueRollback, err = cmd.Flags().GetBool("continue-rollback"); err != nil {
+		return opts, err
+	}
+
+	if opts.SkipResources, err = cmd.Flags().GetStringArray("skip-resource"); err != nil {
+		return opts, err
+	}
+
+	policy, err := prunePolicyFromFlags(cmd)
+	if policy.Keep >= 0 || policy.Failed {
+		opts.Prune = &policy
+	}
+
+	return opts, err
+}
+
+// prunePolicyFromFlags reads the apply prune flags
+func prunePolicyFromFlags(cmd *cobra.Command) (client.PrunePolicy, error) {
+	policy := client.PrunePolicy{}
+
+	var err error
+	if policy.Keep, err = cmd.Flags().GetInt("prune-keep"); err != nil {
+		return policy, err
+	}
+
+	if policy.Failed, err = cmd.Flags().GetBool("prune-failed"); err != nil {
+		return policy, err
+	}
+
+	policy.GracePeriod, err = cmd.Flags().GetDuration("prune-grace-period")
+
+	return policy, err
+}
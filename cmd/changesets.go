@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"context"
+	"os"
+
+	"github.com/massdriver-cloud/fogmachine/pkg/changesets"
+	"github.com/massdriver-cloud/fogmachine/pkg/client"
+	"github.com/massdriver-cloud/fogmachine/pkg/exitcode"
+	"github.com/massdriver-cloud/fogmachine/pkg/plan"
+	"github.com/massdriver-cloud/fogmachine/pkg/redact"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+func ChangesetsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "changesets",
+		Short: "Manage the changesets of a Cloudformation stack",
+		Long:  "List, describe, delete and prune the changesets of a Cloudformation stack",
+	}
+
+	cmd.PersistentFlags().StringP("package-name", "p", "", "Package name")
+	_ = cmd.MarkPersistentFlagRequired("package-name")
+	cmd.PersistentFlags().StringP("region", "r", "", "AWS region")
+	_ = cmd.MarkPersistentFlagRequired("region")
+
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "List the changesets of the stack, newest first",
+		Args:  cobra.NoArgs,
+		Run:   runChangesetsList,
+	}
+	list.Flags().StringP("format", "f", plan.FormatTable, "Output format [table, json]")
+
+	describe := &cobra.Command{
+		Use:   "describe <changeset>",
+		Short: "Describe a changeset and its changes by name or ID",
+		Args:  cobra.ExactArgs(1),
+		Run:   runChangesetsDescribe,
+	}
+	describe.Flags().StringP("format", "f", plan.FormatTable, "Output format [table, json]")
+
+	deleteCmd := &cobra.Command{
+		Use:   "delete <changeset>...",
+		Short: "Delete changesets by name or ID",
+		Args:  cobra.MinimumNArgs(1),
+		Run:   runChangesetsDelete,
+	}
+
+	prune := &cobra.Command{
+		Use:   "prune",
+		Short: "Delete old and failed changesets",
+		Long:  "Delete all but the newest --keep changesets fogmachine created. Changesets that are executing or were executed are never deleted.",
+		Args:  cobra.NoArgs,
+		Run:   runChangesetsPrune,
+	}
+	prune.Flags().Int("keep", 5, "Number of the newest changesets to keep, -1 keeps them all")
+	prune.Flags().Bool("failed", false, "Also delete FAILED changesets --keep would keep")
+	prune.Flags().Duration("grace-period", client.DefaultPruneGracePeriod, "Keep changesets that can still be executed until they're this old")
+	prune.Flags().Bool("all", false, "Also prune changesets fogmachine didn't create and executable ones within the grace period")
+
+	cmd.AddCommand(list, describe, deleteCmd, prune)
+
+	return cmd
+}
+
+func runChangesetsList(cmd *cobra.Command, _ []string) {
+	ctx := context.Background()
+
+	format, err := cmd.Flags().GetString("format")
+	if err != nil {
+		log.Fatal().Err(err).Msg("")
+	}
+
+	cf, err := stackClientFromFlags(ctx, cmd)
+	if err != nil {
+		log.Fatal().Err(err).Msg("")
+	}
+
+	summaries, err := cf.ChangeSets(ctx)
+	if err == nil {
+		err = changesets.RenderList(redact.Writer(os.Stdout), summaries, format)
+	}
+
+	exitcode.Exit(err)
+}
+
+func runChangesetsDescribe(cmd *cobra.Command, args []string) {
+	ctx := context.Background()
+
+	format, err := cmd.Flags().GetString("format")
+	if err != nil {
+		log.Fatal().Err(err).Msg("")
+	}
+
+	cf, err := stackClientFromFlags(ctx, cmd)
+	if err != nil {
+		log.Fatal().Err(err).Msg("")
+	}
+
+	described, err := cf.DescribeChangeSet(ctx, args[0])
+	if err == nil {
+		err = changesets.RenderDescribe(redact.Writer(os.Stdout), described, format)
+	}
+
+	exitcode.Exit(err)
+}
+
+func runChangesetsDelete(cmd *cobra.Command, args []string) {
+	ctx := context.Background()
+
+	cf, err := stackClientFromFlags(ctx, cmd)
+	if err != nil {
+		log.Fatal().Err(err).Msg("")
+	}
+
+	for _, nameOrID := range args {
+		if err = cf.DeleteChangeSet(ctx, nameOrID); err != nil {
+			exitcode.Exit(err)
+		}
+		log.Info().Str("phase", "Changeset").Str("changeset", nameOrID).Msg("Deleted changeset")
+	}
+}
+
+func runChangesetsPrune(cmd *cobra.Command, _ []string) {
+	ctx := context.Background()
+
+	policy, err := prunePolicyFromChangesetsFlags(cmd)
+	if err != nil {
+		log.Fatal().Err(err).Msg("")
+	}
+
+	cf, err := stackClientFromFlags(ctx, cmd)
+	if err != nil {
+		log.Fatal().Err(err).Msg("")
+	}
+
+	pruned, err := cf.PruneChangeSets(ctx, policy)
+	if err == nil {
+		log.Info().Str("phase", "Changeset").Int("pruned", len(pruned)).Msg("Pruned changesets")
+	}
+
+	exitcode.Exit(err)
+}
+
+// prunePolicyFromChangesetsFlags reads the changesets prune flags
+func prunePolicyFromChangesetsFlags(cmd *cobra.Command) (client.PrunePolicy, error) {
+	policy := client.PrunePolicy{}
+
+	var err error
+	if policy.Keep, err = cmd.Flags().GetInt("keep"); err != nil {
+		return policy, err
+	}
+
+	if policy.Failed, err = cmd.Flags().GetBool("failed"); err != nil {
+		return policy, err
+	}
+
+	if policy.GracePeriod, err = cmd.Flags().GetDuration("grace-period"); err != nil {
+		return policy, err
+	}
+
+	policy.All, err = cmd.Flags().GetBool("all")
+
+	return policy, err
+}
@@ -1,7 +1,11 @@
 package cmd
 
 import (
+	"context"
+
 	"github.com/massdriver-cloud/fogmachine/pkg/destroy"
+	"github.com/massdriver-cloud/fogmachine/pkg/exitcode"
+	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 )
 
@@ -10,7 +14,7 @@ func DestroyCmd() *cobra.Command {
 		Use:   "destroy",
 		Short: "Destroy a Cloudformation stack",
 		Long:  "Destroy a Cloudformation stack",
-		Run:   destroy.Destroy,
+		Run:   runDestroy,
 	}
 
 	cmd.Flags().StringP("package-name", "p", "", "Package name")
@@ -19,6 +23,23 @@ func DestroyCmd() *cobra.Command {
 	_ = cmd.MarkFlagRequired("region")
 	cmd.Flags().Int("timeout", 600, "time in seconds to wait for resources to finish, this does not cancel the cloud formation run")
 	cmd.Flags().Int("poll-interval", 3, "time in seconds between each poll of the AWS api for updates")
+	addBusyFlags(cmd)
+	addEventFlags(cmd)
 
 	return cmd
 }
+
+func runDestroy(cmd *cobra.Command, _ []string) {
+	cfg, err := clientConfigFromFlags(cmd)
+	if err != nil {
+		log.Fatal().Err(err).Msg("")
+	}
+
+	err = withLock(cmd, cfg, func() error {
+		_, destroyErr := destroy.Destroy(context.Background(), destroy.Options{Config: cfg})
+		return destroyErr
+	})
+	closeEvents(cfg.Events)
+
+	exitcode.Exit(err)
+}
@@ -0,0 +1,405 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/massdriver-cloud/fogmachine/pkg/client"
+	"github.com/massdriver-cloud/fogmachine/pkg/lock"
+	"github.com/massdriver-cloud/fogmachine/pkg/redact"
+	"github.com/massdriver-cloud/fogmachine/pkg/stackconfig"
+	"github.com/massdriver-cloud/fogmachine/pkg/template"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// Output formats of the --output flag
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+// addStackConfigFlags adds the flags for the stack settings passed to every changeset
+func addStackConfigFlags(cmd *cobra.Command) {
+	cmd.Flags().String("stack-config", "", "Path to a YAML or JSON file with capabilities, tags, role ARN, notification ARNs and rollback configuration")
+	cmd.Flags().StringSlice("capabilities", nil, "Capabilities to acknowledge [CAPABILITY_IAM, CAPABILITY_NAMED_IAM, CAPABILITY_AUTO_EXPAND]")
+	cmd.Flags().StringArray("tag", nil, "Stack tag as Key=Value, can be repeated")
+	cmd.Flags().String("role-arn", "", "ARN of the IAM role CloudFormation assumes to change the stack")
+	cmd.Flags().StringArray("notification-arn", nil, "ARN of an SNS topic to send stack events to, can be repeated")
+	cmd.Flags().StringArray("rollback-trigger", nil, "ARN of a CloudWatch alarm that rolls the stack back, can be repeated")
+	cmd.Flags().Int32("rollback-monitoring-minutes", 0, "Minutes to monitor the rollback triggers after the stack is changed")
+	cmd.Flags().Bool("include-nested-stacks", false, "Create changesets for nested stacks too")
+}
+
+// addParameterFlags adds the flags for the parameter files and how they are read
+func addParameterFlags(cmd *cobra.Command) {
+	cmd.Flags().StringArray("parameter-path", nil, "Path to CloudFormation input vars, can be repeated and later files are deep merged over earlier ones")
+	cmd.Flags().StringArray("parameter", nil, "Parameter as Key=Value, overrides the parameter files and FOGMACHINE_PARAM_ variables, can be repeated")
+	cmd.Flags().Bool("use-previous-values", true, "Parameters that aren't set keep the value they have on the existing stack instead of the template default")
+	cmd.Flags().StringArray("use-previous-value", nil, "Keep the existing value of this parameter when it isn't set, for use with --use-previous-values=false, can be repeated")
+	cmd.Flags().StringArray("reset-parameter", nil, "Use the template default for this parameter when it isn't set instead of its existing value, can be repeated")
+	cmd.Flags().Bool("show-effective-params", false, "Print the merged parameters and where each value was set, NoEcho values are redacted")
+	cmd.Flags().String("nested-parameters", string(template.NestedFlatten), "How nested objects in the parameter file are set [flatten, json, reject]")
+	cmd.Flags().String("parameter-mapping", string(template.MappingPascal), "How nested values are matched to template parameters [pascal, table, jsonpath]")
+	cmd.Flags().String("parameter-mapping-file", "", "Mapping file for the table and jsonpath parameter mappings")
+}
+
+// addEventFlags adds the flags for the event sinks besides the console and --output json
+func addEventFlags(cmd *cobra.Command) {
+	cmd.Flags().String("events-file", "", "Write every event to this file as newline delimited JSON")
+	cmd.Flags().String("webhook-url", "", "POST batches of events as JSON to this URL")
+	cmd.Flags().String("webhook-secret", "", "Sign webhook requests with HMAC-SHA256 using this secret, defaults to $FOGMACHINE_WEBHOOK_SECRET")
+	cmd.Flags().Int("webhook-batch-size", 20, "Most events sent in one webhook request")
+}
+
+// addBusyFlags adds the flags for what to do when another operation is running on the stack
+func addBusyFlags(cmd *cobra.Command) {
+	cmd.Flags().String("on-busy", string(client.BusyFail), "What to do when another operation is running on the stack [fail, wait]")
+	cmd.Flags().Int("busy-timeout", 0, "time in seconds --on-busy wait waits for the other operation, defaults to --timeout")
+	cmd.Flags().String("lock-file", "", "Hold this lock file while running so runs on the same host don't change the stack at once")
+}
+
+// clientConfigFromFlags reads the stack, region, timing and event sink flags, and the busy flags of the commands
+// that have them. The caller closes the event sink.
+func clientConfigFromFlags(cmd *cobra.Command) (client.Config, error) {
+	// Events are redacted with the default redactor so the --redact patterns apply to them too
+	cfg := client.Config{Redactor: redact.Default()}
+
+	var err error
+	if cfg.StackName, err = cmd.Flags().GetString("package-name"); err != nil {
+		return cfg, err
+	}
+
+	if cfg.Region, err = cmd.Flags().GetString("region"); err != nil {
+		return cfg, err
+	}
+
+	timeout, err := cmd.Flags().GetInt("timeout")
+	if err != nil {
+		return cfg, err
+	}
+	cfg.Timeout = time.Duration(timeout) * time.Second
+
+	pollInterval, err := cmd.Flags().GetInt("poll-interval")
+	if err != nil {
+		return cfg, err
+	}
+	cfg.PollInterval = time.Duration(pollInterval) * time.Second
+
+	if cmd.Flags().Lookup("on-busy") != nil {
+		if cfg, err = busyConfigFromFlags(cmd, cfg); err != nil {
+			return cfg, err
+		}
+	}
+
+	cfg.Events, err = eventSinkFromFlags(cmd)
+
+	return cfg, err
+}
+
+// eventSinkFromFlags builds the sinks the --output, --events-file and --webhook-* flags ask for. Events are always
+// logged to the console.
+func eventSinkFromFlags(cmd *cobra.Command) (client.EventSink, error) {
+	output, err := cmd.Flags().GetString("output")
+	if err != nil {
+		return nil, err
+	}
+
+	sinks := client.MultiSink{client.ConsoleSink{}}
+
+	switch output {
+	case outputText, "":
+	case outputJSON:
+		sinks = append(sinks, client.NewNDJSONSink(os.Stdout))
+	default:
+		return nil, fmt.Errorf("unknown output %q, expected one of [%s, %s]", output, outputText, outputJSON)
+	}
+
+	eventsFile, err := cmd.Flags().GetString("events-file")
+	if err != nil {
+		return nil, err
+	}
+
+	if eventsFile != "" {
+		fileSink, fileErr := client.NewFileSink(eventsFile)
+		if fileErr != nil {
+			return nil, fileErr
+		}
+		sinks = append(sinks, fileSink)
+	}
+
+	webhook, err := webhookOptionsFromFlags(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	if webhook.URL != "" {
+		sinks = append(sinks, client.NewWebhookSink(webhook))
+	}
+
+	return sinks, nil
+}
+
+// webhookOptionsFromFlags reads the --webhook-* flags, the secret is registered with the redactor
+func webhookOptionsFromFlags(cmd *cobra.Command) (client.WebhookOptions, error) {
+	opts := client.WebhookOptions{}
+
+	var err error
+	if opts.URL, err = cmd.Flags().GetString("webhook-url"); err != nil {
+		return opts, err
+	}
+
+	if opts.Secret, err = cmd.Flags().GetString("webhook-secret"); err != nil {
+		return opts, err
+	}
+	if opts.Secret == "" {
+		opts.Secret = os.Getenv(client.WebhookSecretEnv)
+	}
+	redact.AddValues(opts.Secret)
+
+	if opts.BatchSize, err = cmd.Flags().GetInt("webhook-batch-size"); err != nil {
+		return opts, err
+	}
+
+	return opts, nil
+}
+
+// busyConfigFromFlags reads what to do when another operation is running on the stack
+func busyConfigFromFlags(cmd *cobra.Command, cfg client.Config) (client.Config, error) {
+	onBusy, err := cmd.Flags().GetString("on-busy")
+	if err != nil {
+		return cfg, err
+	}
+	cfg.OnBusy = client.BusyPolicy(onBusy)
+	if cfg.OnBusy != client.BusyFail && cfg.OnBusy != client.BusyWait {
+		return cfg, fmt.Errorf("unknown --on-busy %q, expected one of [%s, %s]", onBusy, client.BusyFail, client.BusyWait)
+	}
+
+	busyTimeout, err := cmd.Flags().GetInt("busy-timeout")
+	if err != nil {
+		return cfg, err
+	}
+	cfg.BusyTimeout = time.Duration(busyTimeout) * time.Second
+
+	return cfg, nil
+}
+
+// templateInputFromFlags reads the template and parameter flags shared by plan and apply
+func templateInputFromFlags(cmd *cobra.Command) (template.Input, error) {
+	input := template.Input{Environment: os.Environ()}
+
+	var err error
+	if input.TemplatePath, err = cmd.Flags().GetString("template-path"); err != nil {
+		return input, err
+	}
+
+	if input.ParameterPaths, err = cmd.Flags().GetStringArray("parameter-path"); err != nil {
+		return input, err
+	}
+
+	if input.Overrides, err = cmd.Flags().GetStringArray("parameter"); err != nil {
+		return input, err
+	}
+
+	nested, err := cmd.Flags().GetString("nested-parameters")
+	if err != nil {
+		return input, err
+	}
+
+	if input.NestedPolicy, err = template.ParseNestedPolicy(nested); err != nil {
+		return input, err
+	}
+
+	mapping, err := cmd.Flags().GetString("parameter-mapping")
+	if err != nil {
+		return input, err
+	}
+
+	if input.Mapping, err = template.ParseMappingStrategy(mapping); err != nil {
+		return input, err
+	}
+
+	if input.MappingPath, err = cmd.Flags().GetString("parameter-mapping-file"); err != nil {
+		return input, err
+	}
+
+	input.Previous, err = previousValuesFromFlags(cmd)
+
+	return input, err
+}
+
+// previousValuesFromFlags reads which parameters keep the value they have on the existing stack
+func previousValuesFromFlags(cmd *cobra.Command) (template.PreviousValues, error) {
+	previous := template.PreviousValues{}
+
+	var err error
+	if previous.Omitted, err = cmd.Flags().GetBool("use-previous-values"); err != nil {
+		return previous, err
+	}
+
+	if previous.Keep, err = cmd.Flags().GetStringArray("use-previous-value"); err != nil {
+		return previous, err
+	}
+
+	previous.Reset, err = cmd.Flags().GetStringArray("reset-parameter")
+
+	return previous, err
+}
+
+// stackOptionsFromFlags builds the stack options from the --stack-config file with the individual flags layered
+// on top
+func stackOptionsFromFlags(cmd *cobra.Command) (client.StackOptions, error) {
+	config := &stackconfig.Config{}
+
+	path, err := cmd.Flags().GetString("stack-config")
+	if err != nil {
+		return client.StackOptions{}, err
+	}
+
+	if path != "" {
+		if config, err = stackconfig.Read(path); err != nil {
+			return client.StackOptions{}, err
+		}
+	}
+
+	flags, err := stackConfigFromFlags(cmd)
+	if err != nil {
+		return client.StackOptions{}, err
+	}
+	config.Merge(flags)
+
+	options := config.StackOptions()
+
+	return options, options.Validate()
+}
+
+// stackConfigFromFlags reads the individual stack setting flags
+func stackConfigFromFlags(cmd *cobra.Command) (stackconfig.Config, error) {
+	config := stackconfig.Config{}
+
+	var err error
+	if config.Capabilities, err = cmd.Flags().GetStringSlice("capabilities"); err != nil {
+		return config, err
+	}
+
+	tags, err := cmd.Flags().GetStringArray("tag")
+	if err != nil {
+		return config, err
+	}
+
+	if config.Tags, err = stackconfig.ParseTags(tags); err != nil {
+		return config, err
+	}
+
+	if config.RoleARN, err = cmd.Flags().GetString("role-arn"); err != nil {
+		return config, err
+	}
+
+	if config.NotificationARNs, err = cmd.Flags().GetStringArray("notification-arn"); err != nil {
+		return config, err
+	}
+
+	if err = mergeRollbackFlags(cmd, &config); err != nil {
+		return config, err
+	}
+
+	config.IncludeNestedStacks, err = cmd.Flags().GetBool("include-nested-stacks")
+
+	return config, err
+}
+
+// mergeRollbackFlags sets the rollback configuration of config from the rollback flags, it's left alone when none
+// are set
+func mergeRollbackFlags(cmd *cobra.Command, config *stackconfig.Config) error {
+	triggers, err := cmd.Flags().GetStringArray("rollback-trigger")
+	if err != nil {
+		return err
+	}
+
+	monitoring, err := cmd.Flags().GetInt32("rollback-monitoring-minutes")
+	if err != nil {
+		return err
+	}
+
+	if len(triggers) == 0 && !cmd.Flags().Changed("rollback-monitoring-minutes") {
+		return nil
+	}
+
+	config.Rollback = &stackconfig.Rollback{MonitoringTimeInMinutes: monitoring}
+	for _, arn := range triggers {
+		config.Rollback.Triggers = append(config.Rollback.Triggers, stackconfig.RollbackTrigger{Arn: arn})
+	}
+
+	return nil
+}
+
+// effectiveParametersFromFlags returns where --show-effective-params prints the effective parameters, nil when
+// they aren't printed
+func effectiveParametersFromFlags(cmd *cobra.Command) (io.Writer, error) {
+	show, err := cmd.Flags().GetBool("show-effective-params")
+	if err != nil || !show {
+		return nil, err
+	}
+
+	return redact.Writer(os.Stderr), nil
+}
+
+// withLock runs fn holding the --lock-file lock when one is set. With --on-busy wait it waits for another run to
+// release the lock as long as it would wait for the stack.
+func withLock(cmd *cobra.Command, cfg client.Config, fn func() error) error {
+	path, err := cmd.Flags().GetString("lock-file")
+	if err != nil {
+		return err
+	}
+
+	if path == "" {
+		return fn()
+	}
+
+	wait := time.Duration(0)
+	if cfg.OnBusy == client.BusyWait {
+		wait = cfg.BusyTimeout
+		if wait <= 0 {
+			wait = cfg.Timeout
+		}
+	}
+
+	held, err := lock.Acquire(path, cfg.StackName, wait, cfg.PollInterval)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if releaseErr := held.Release(); releaseErr != nil {
+			log.Warn().Err(releaseErr).Str("lockFile", path).Msg("unable to release the lock")
+		}
+	}()
+
+	return fn()
+}
+
+// closeEvents flushes the event sinks, a sink that can't be flushed doesn't change how the command exits
+func closeEvents(sink client.EventSink) {
+	if err := sink.Close(); err != nil {
+		log.Warn().Err(err).Msg("unable to send events")
+	}
+}
+
+// stackClientFromFlags creates a client for the --package-name stack in --region, for the commands that read and
+// change the stack without waiting on it
+func stackClientFromFlags(ctx context.Context, cmd *cobra.Command) (*client.Client, error) {
+	stackName, err := cmd.Flags().GetString("package-name")
+	if err != nil {
+		return nil, err
+	}
+
+	region, err := cmd.Flags().GetString("region")
+	if err != nil {
+		return nil, err
+	}
+
+	return client.New(ctx, client.Config{StackName: stackName, Region: region, Redactor: redact.Default()})
+}
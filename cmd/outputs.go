@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"github.com/massdriver-cloud/fogmachine/pkg/exitcode"
+	"github.com/massdriver-cloud/fogmachine/pkg/outputs"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+func OutputsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "outputs",
+		Short: "Write the outputs of a Cloudformation stack",
+		Long:  "Write the outputs of a Cloudformation stack as JSON, YAML, dotenv or GitHub Actions outputs",
+		Run:   runOutputs,
+	}
+
+	cmd.Flags().StringP("package-name", "p", "", "Package name")
+	_ = cmd.MarkFlagRequired("package-name")
+	cmd.Flags().StringP("region", "r", "", "AWS region")
+	_ = cmd.MarkFlagRequired("region")
+	cmd.Flags().StringP("format", "f", outputs.FormatJSON, "Output format [json, yaml, dotenv, github]")
+	cmd.Flags().String("file", "", "File to write the outputs to, defaults to stdout or $GITHUB_OUTPUT for the github format")
+	cmd.Flags().Bool("include-export-names", false, "Include the export name of each output")
+	cmd.Flags().Bool("include-descriptions", false, "Include the description of each output")
+
+	return cmd
+}
+
+func runOutputs(cmd *cobra.Command, _ []string) {
+	ctx := context.Background()
+
+	path, err := cmd.Flags().GetString("file")
+	if err != nil {
+		log.Fatal().Err(err).Msg("")
+	}
+
+	opts, err := outputOptionsFromFlags(cmd, "format", "include-export-names", "include-descriptions")
+	if err != nil {
+		log.Fatal().Err(err).Msg("")
+	}
+
+	if path == "" && opts.Format == outputs.FormatGitHub {
+		path = os.Getenv("GITHUB_OUTPUT")
+		if path == "" {
+			log.Fatal().Err(errors.New("--file is required for the github format when GITHUB_OUTPUT is not set")).Msg("")
+		}
+	}
+
+	cf, err := stackClientFromFlags(ctx, cmd)
+	if err != nil {
+		log.Fatal().Err(err).Msg("")
+	}
+
+	exitcode.Exit(outputs.Save(ctx, cf, path, opts))
+}
+
+// outputOptionsFromFlags reads the output options from the named flags and checks the format
+func outputOptionsFromFlags(cmd *cobra.Command, formatFlag, exportNamesFlag, descriptionsFlag string) (outputs.Options, error) {
+	format, err := cmd.Flags().GetString(formatFlag)
+	if err != nil {
+		return outputs.Options{}, err
+	}
+
+	exportNames, err := cmd.Flags().GetBool(exportNamesFlag)
+	if err != nil {
+		return outputs.Options{}, err
+	}
+
+	descriptions, err := cmd.Flags().GetBool(descriptionsFlag)
+	if err != nil {
+		return outputs.Options{}, err
+	}
+
+	opts := outputs.Options{
+		Format:              format,
+		IncludeExportNames:  exportNames,
+		IncludeDescriptions: descriptions,
+	}
+
+	return opts, opts.Validate()
+}
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"context"
+	"os"
+
+	"github.com/massdriver-cloud/fogmachine/pkg/exitcode"
+	"github.com/massdriver-cloud/fogmachine/pkg/plan"
+	"github.com/massdriver-cloud/fogmachine/pkg/redact"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+func PlanCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "plan",
+		Short: "Preview changes to a Cloudformation stack",
+		Long:  "Create a changeset for a Cloudformation stack and print the changes it would make without executing it",
+		Run:   runPlan,
+	}
+
+	cmd.Flags().StringP("package-name", "p", "", "Package name")
+	_ = cmd.MarkFlagRequired("package-name")
+	cmd.Flags().StringP("region", "r", "", "AWS region")
+	_ = cmd.MarkFlagRequired("region")
+	cmd.Flags().StringP("template-path", "", "", "Path to CloudFormation template")
+	_ = cmd.MarkFlagRequired("template-path")
+	cmd.Flags().StringP("format", "f", plan.FormatTable, "Output format for the changes [table, json]")
+	cmd.Flags().String("plan-file", "", "Write the changeset ID and template and parameter hashes to this file for a later apply --plan-file")
+	cmd.Flags().Bool("delete-empty-changeset", false, "Delete the changeset when it contains no changes instead of leaving it on the stack")
+	addParameterFlags(cmd)
+	addStackConfigFlags(cmd)
+	cmd.Flags().Int("timeout", 600, "time in seconds to wait for resources to finish, this does not cancel the cloud formation run")
+	cmd.Flags().Int("poll-interval", 3, "time in seconds between each poll of the AWS api for updates")
+	addEventFlags(cmd)
+
+	return cmd
+}
+
+func runPlan(cmd *cobra.Command, _ []string) {
+	// The format is checked before the changeset is created
+	format, err := cmd.Flags().GetString("format")
+	if err == nil {
+		err = plan.ValidateFormat(format)
+	}
+	if err != nil {
+		log.Fatal().Err(err).Msg("")
+	}
+
+	opts, err := planOptionsFromFlags(cmd)
+	if err != nil {
+		log.Fatal().Err(err).Msg("")
+	}
+
+	result, err := plan.Plan(context.Background(), opts)
+	if err == nil && !opts.EmitChanges {
+		if err = plan.WriteParameterChanges(redact.Writer(os.Stdout), result.Parameters, format); err == nil {
+			err = plan.Render(redact.Writer(os.Stdout), result.Changes, format)
+		}
+	}
+	closeEvents(opts.Events)
+
+	exitcode.Exit(err)
+}
+
+// planOptionsFromFlags reads the plan flags. The caller closes the event sink.
+func planOptionsFromFlags(cmd *cobra.Command) (plan.Options, error) {
+	opts := plan.Options{}
+
+	var err error
+	if opts.PlanFile, err = cmd.Flags().GetString("plan-file"); err != nil {
+		return opts, err
+	}
+
+	output, err := cmd.Flags().GetString("output")
+	if err != nil {
+		return opts, err
+	}
+	opts.EmitChanges = output == outputJSON
+
+	if opts.Template, err = templateInputFromFlags(cmd); err != nil {
+		return opts, err
+	}
+
+	if opts.Stack, err = stackOptionsFromFlags(cmd); err != nil {
+		return opts, err
+	}
+
+	if opts.EffectiveParameters, err = effectiveParametersFromFlags(cmd); err != nil {
+		return opts, err
+	}
+
+	if opts.Config, err = clientConfigFromFlags(cmd); err != nil {
+		return opts, err
+	}
+
+	opts.DeleteEmptyChangeset, err = cmd.Flags().GetBool("delete-empty-changeset")
+
+	return opts, err
+}
@@ -6,6 +6,7 @@ package cmd
 import (
 	"os"
 
+	"github.com/massdriver-cloud/fogmachine/pkg/redact"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/rs/zerolog/pkgerrors"
@@ -20,6 +21,8 @@ func NewCmdFogMachine() *cobra.Command {
 	}
 
 	rootCmd.PersistentFlags().StringP("log-level", "l", "info", "Set the log level [debug, info, warn, error]")
+	rootCmd.PersistentFlags().StringP("output", "o", "text", "Event output [text, json], json writes newline delimited JSON events to stdout")
+	rootCmd.PersistentFlags().StringArray("redact", nil, "Regular expression for text to redact from logs and reports, can be repeated")
 
 	rootCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
 		logLevel, err := cmd.Flags().GetString("log-level")
@@ -27,11 +30,23 @@ func NewCmdFogMachine() *cobra.Command {
 			log.Fatal().Err(err).Msg("")
 		}
 		initLogging(logLevel)
+
+		patterns, err := cmd.Flags().GetStringArray("redact")
+		if err != nil {
+			log.Fatal().Err(err).Msg("")
+		}
+		if err = redact.AddPatterns(patterns...); err != nil {
+			log.Fatal().Err(err).Msg("")
+		}
 	}
 
 	rootCmd.AddCommand(
 		ApplyCmd(),
+		PlanCmd(),
 		DestroyCmd(),
+		OutputsCmd(),
+		ChangesetsCmd(),
+		ValidateCmd(),
 		VersionCmd(),
 	)
 
@@ -57,5 +72,5 @@ func initLogging(level string) {
 	zerolog.SetGlobalLevel(l)
 	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
 	zerolog.ErrorStackMarshaler = pkgerrors.MarshalStack
-	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
+	log.Logger = log.Output(zerolog.ConsoleWriter{Out: redact.Writer(os.Stderr)})
 }
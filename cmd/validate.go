@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"context"
+	"os"
+
+	"github.com/massdriver-cloud/fogmachine/pkg/exitcode"
+	"github.com/massdriver-cloud/fogmachine/pkg/redact"
+	"github.com/massdriver-cloud/fogmachine/pkg/validate"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+func ValidateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "validate",
+		Short: "Validate a Cloudformation template",
+		Long:  "Check a Cloudformation template locally for syntax errors, unknown intrinsic functions, missing Ref and GetAtt targets and unused parameters, then optionally with the CloudFormation API",
+		Run:   runValidate,
+	}
+
+	cmd.Flags().StringP("template-path", "", "", "Path to CloudFormation template")
+	_ = cmd.MarkFlagRequired("template-path")
+	cmd.Flags().Bool("remote", false, "Also validate the template with the CloudFormation ValidateTemplate API")
+	cmd.Flags().StringP("region", "r", "", "AWS region, required with --remote")
+
+	return cmd
+}
+
+func runValidate(cmd *cobra.Command, _ []string) {
+	opts := validate.Options{}
+
+	var err error
+	if opts.TemplatePath, err = cmd.Flags().GetString("template-path"); err != nil {
+		log.Fatal().Err(err).Msg("")
+	}
+
+	if opts.Remote, err = cmd.Flags().GetBool("remote"); err != nil {
+		log.Fatal().Err(err).Msg("")
+	}
+
+	if opts.Region, err = cmd.Flags().GetString("region"); err != nil {
+		log.Fatal().Err(err).Msg("")
+	}
+
+	result, err := validate.Validate(context.Background(), opts)
+	if result != nil {
+		if writeErr := result.Write(redact.Writer(os.Stdout)); err == nil {
+			err = writeErr
+		}
+	}
+
+	exitcode.Exit(err)
+}
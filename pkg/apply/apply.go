@@ -2,63 +2,253 @@ package apply
 
 import (
 	"context"
+	"errors"
+	"io"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
 	"github.com/massdriver-cloud/fogmachine/pkg/client"
+	"github.com/massdriver-cloud/fogmachine/pkg/plan"
 	"github.com/massdriver-cloud/fogmachine/pkg/template"
 	"github.com/rs/zerolog/log"
-	"github.com/spf13/cobra"
 )
 
-func CfApply(cmd *cobra.Command, _ []string) {
-	ctx := context.Background()
-	packageName, err := cmd.Flags().GetString("package-name")
+// Options configure Apply
+type Options struct {
+	client.Config
+
+	// Template is the template and parameters to apply. It's required unless PlanFile is set, then it's
+	// only read, and checked against the plan, when its TemplatePath is set.
+	Template template.Input
+	// PlanFile executes the changeset recorded by plan --plan-file instead of creating a new one
+	PlanFile string
+	// Stack is passed to the changeset, tags from the parameter files are added underneath its tags
+	Stack client.StackOptions
+	// EffectiveParameters is where the merged parameters are printed, they aren't printed when it's nil
+	EffectiveParameters io.Writer
+	// RecreateFailed deletes a stack that was never created successfully, in ROLLBACK_COMPLETE, ROLLBACK_FAILED or
+	// CREATE_FAILED, so it can be created again. Without it apply fails with a NotUpdatableError.
+	RecreateFailed bool
+	// ContinueRollback finishes the rollback of a stack in UPDATE_ROLLBACK_FAILED before updating it, skipping
+	// the resources in SkipResources
+	ContinueRollback bool
+	SkipResources    []string
+	// Prune deletes the changesets of the stack the policy doesn't keep once the apply ends, nothing is pruned
+	// when it's nil. A failed prune is logged and doesn't fail the apply.
+	Prune *client.PrunePolicy
+}
+
+// Result is how an apply ended
+type Result struct {
+	StackName   string
+	OperationID string
+	ChangesetID string
+	// Status is the status the stack finished in, empty when the apply didn't get as far as the stack finishing
+	Status types.StackStatus
+	// NoChanges is set when the changeset had nothing to change, the changeset isn't executed
+	NoChanges bool
+	Changes   []types.Change
+	// Outputs are the outputs of the stack once it's up to date
+	Outputs []types.Output
+	// Failures are the root causes of a failed apply
+	Failures []client.Failure
+	// Pruned are the IDs of the changesets Prune deleted
+	Pruned []string
+}
+
+// Apply creates a changeset, or adopts the one in the plan file, and executes it. It returns the result so far
+// along with any error, a changeset without changes isn't an error. Progress is sent to opts.Events.
+func Apply(ctx context.Context, opts Options) (*Result, error) {
+	cf, err := client.New(ctx, opts.Config)
 	if err != nil {
-		log.Fatal().Err(err).Msg("")
+		return nil, err
 	}
 
-	region, err := cmd.Flags().GetString("region")
-	if err != nil {
-		log.Fatal().Err(err).Msg("")
+	result := &Result{StackName: opts.StackName, OperationID: cf.OperationID()}
+
+	if err = cf.WaitUntilIdle(ctx); err != nil {
+		return result, err
 	}
 
-	timeout, err := cmd.Flags().GetInt("timeout")
-	if err != nil {
-		log.Fatal().Err(err).Msg("")
+	// A stack another operation is still changing isn't pruned, its changesets may be in use
+	if opts.Prune != nil {
+		defer prune(ctx, cf, *opts.Prune, result)
+	}
+
+	var tmpl *template.Output
+	if opts.PlanFile != "" {
+		tmpl, err = adoptPlan(ctx, cf, opts)
+	} else {
+		tmpl, err = createChangeset(ctx, cf, opts)
+	}
+	result.NoChanges = errors.Is(err, client.ErrNoChanges)
+	if err != nil && !result.NoChanges {
+		return result, err
+	}
+
+	result.ChangesetID = cf.ChangesetID()
+
+	if !result.NoChanges {
+		if err = execute(ctx, cf, result); err != nil {
+			return result, err
+		}
+	}
+
+	if err = finish(ctx, cf, tmpl, result); err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// execute lists the changes and executes the changeset, a changeset without changes sets NoChanges and isn't an error
+func execute(ctx context.Context, cf *client.Client, result *Result) error {
+	var err error
+	if result.Changes, err = cf.Changes(ctx); err != nil {
+		return err
+	}
+
+	err = cf.ExecuteChangeSet(ctx)
+	if errors.Is(err, client.ErrNoChanges) {
+		result.NoChanges = true
+		return nil
+	}
+
+	var statusErr *client.StackStatusError
+	if errors.As(err, &statusErr) {
+		result.Status, result.Failures = statusErr.Status, statusErr.Failures
+	}
+
+	return err
+}
+
+// prune deletes the changesets the policy doesn't keep, it runs however the apply ended
+func prune(ctx context.Context, cf *client.Client, policy client.PrunePolicy, result *Result) {
+	pruned, err := cf.PruneChangeSets(ctx, policy)
+	for _, summary := range pruned {
+		result.Pruned = append(result.Pruned, aws.ToString(summary.ChangeSetId))
 	}
 
-	pollInterval, err := cmd.Flags().GetInt("poll-interval")
 	if err != nil {
-		log.Fatal().Err(err).Msg("")
+		log.Warn().Err(err).Msg("unable to prune changesets")
+	}
+}
+
+// finish sets the stack policy from the parameter file and reads the stack once it's up to date
+func finish(ctx context.Context, cf *client.Client, tmpl *template.Output, result *Result) error {
+	if tmpl != nil && len(tmpl.StackPolicy) > 0 {
+		if err := cf.SetStackPolicy(ctx, tmpl.StackPolicy); err != nil {
+			return err
+		}
 	}
 
-	client, err := client.NewCloudformationClient(ctx, packageName, region, timeout, pollInterval)
+	stack, err := cf.Stack(ctx)
 	if err != nil {
-		log.Fatal().Err(err).Msg("")
+		return err
 	}
 
-	templatePath, err := cmd.Flags().GetString("template-path")
+	result.Status, result.Outputs = stack.StackStatus, stack.Outputs
+
+	return nil
+}
+
+func createChangeset(ctx context.Context, cf *client.Client, opts Options) (*template.Output, error) {
+	if opts.Template.TemplatePath == "" {
+		return nil, errors.New("--template-path is required unless --plan-file is set")
+	}
+
+	status, err := cf.StackStatus(ctx)
 	if err != nil {
-		log.Fatal().Err(err).Msg("")
+		return nil, err
+	}
+
+	// The template is read and checked before the stack is recovered, so a mistake in it doesn't cost the stack.
+	// A stack that's recreated has no previous values to keep.
+	existing := []types.Parameter{}
+	if !recreates(status, opts) {
+		if existing, err = cf.StackParameters(ctx); err != nil {
+			return nil, err
+		}
 	}
 
-	parameterPath, err := cmd.Flags().GetString("parameter-path")
+	tmpl, err := readTemplateWith(cf, opts, existing)
 	if err != nil {
-		log.Fatal().Err(err).Msg("")
+		return nil, err
+	}
+
+	if err = recoverStack(ctx, cf, status, opts); err != nil {
+		return nil, err
+	}
+
+	return tmpl, cf.CreateChangeset(ctx, tmpl.Template, tmpl.Parameters, opts.Stack.WithDefaultTags(tmpl.Tags))
+}
+
+// recreates reports whether the options have the stack deleted and created again because it was never created
+func recreates(status types.StackStatus, opts Options) bool {
+	neverCreated := status == types.StackStatusRollbackComplete || status == types.StackStatusRollbackFailed || status == types.StackStatusCreateFailed
+	return neverCreated && opts.RecreateFailed
+}
+
+// recoverStack gets a stack the options allow recovering into a status a changeset can change. Any other stack is
+// left for CreateChangeset to reject.
+func recoverStack(ctx context.Context, cf *client.Client, status types.StackStatus, opts Options) error {
+	if client.Updatable(status) {
+		return nil
 	}
 
-	template, err := template.Read(template.Input{
-		TemplatePath:  templatePath,
-		ParameterPath: parameterPath,
-	})
+	switch {
+	case recreates(status, opts):
+		log.Info().Str("phase", "Execution").Str("status", string(status)).Msg("Deleting the stack to create it again")
+		return cf.ExecuteDestroyStack(ctx)
+	case status == types.StackStatusUpdateRollbackFailed && opts.ContinueRollback:
+		return cf.ContinueUpdateRollback(ctx, opts.SkipResources)
+	default:
+		return nil
+	}
+}
+
+// adoptPlan verifies the plan file against the stack, region and optionally the template,
+// then points the client at the changeset the plan created. The template is only read when it's passed.
+func adoptPlan(ctx context.Context, cf *client.Client, opts Options) (*template.Output, error) {
+	planned, err := plan.ReadFile(opts.PlanFile)
 	if err != nil {
-		log.Fatal().Err(err).Msg("")
+		return nil, err
+	}
+
+	var tmpl *template.Output
+	if opts.Template.TemplatePath != "" {
+		tmpl, err = readTemplate(ctx, cf, opts)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	if err = client.CreateChangeset(ctx, template.Template, template.Parameters); err != nil {
-		log.Fatal().Err(err).Msg("")
+	if err = planned.Verify(opts.StackName, opts.Region, tmpl); err != nil {
+		return nil, err
 	}
 
-	if err = client.ExecuteChangeSet(ctx); err != nil {
-		log.Fatal().Err(err).Msg("")
+	return tmpl, cf.AdoptChangeset(ctx, planned.ChangesetID)
+}
+
+// readTemplate reads the template and parameters, keeping the previous values of the existing stack
+func readTemplate(ctx context.Context, cf *client.Client, opts Options) (*template.Output, error) {
+	existing, err := cf.StackParameters(ctx)
+	if err != nil {
+		return nil, err
 	}
+
+	return readTemplateWith(cf, opts, existing)
+}
+
+// readTemplateWith reads the template and parameters, keeping the previous values of the existing parameters. The
+// values of NoEcho parameters are registered with the client's redactor.
+func readTemplateWith(cf *client.Client, opts Options, existing []types.Parameter) (*template.Output, error) {
+	tmpl, err := plan.ReadTemplate(opts.Template, existing, opts.EffectiveParameters)
+	if err != nil {
+		return nil, err
+	}
+
+	cf.Redactor().AddValues(tmpl.Secrets...)
+
+	return tmpl, nil
 }
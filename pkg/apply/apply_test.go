@@ -0,0 +1,381 @@
+package apply_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/massdriver-cloud/fogmachine/pkg/apply"
+	"github.com/massdriver-cloud/fogmachine/pkg/client"
+	"github.com/massdriver-cloud/fogmachine/pkg/plan"
+	"github.com/massdriver-cloud/fogmachine/pkg/redact"
+	"github.com/massdriver-cloud/fogmachine/pkg/template"
+	"github.com/massdriver-cloud/fogmachine/pkg/testing/fake"
+)
+
+func TestApply(t *testing.T) {
+	cf := fake.New()
+	if _, err := apply.Apply(context.Background(), fakeOptions(cf)); err != nil {
+		t.Fatal(err)
+	}
+	cf.SetOutputs("bar", map[string]string{"BucketName": "bar-bucket"})
+
+	var mu sync.Mutex
+	events := []client.Event{}
+
+	opts := fakeOptions(cf, "BucketName=bar-bucket")
+	opts.Events = client.FuncSink(func(event client.Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event)
+	})
+
+	result, err := apply.Apply(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Status != types.StackStatusUpdateComplete {
+		t.Fatalf("Got %s but expected %s", result.Status, types.StackStatusUpdateComplete)
+	}
+
+	if result.NoChanges || len(result.Changes) != 1 || result.Changes[0].ResourceChange.Action != types.ChangeActionModify {
+		t.Fatalf("Got %d changes but expected MainBucket to be modified", len(result.Changes))
+	}
+
+	if len(result.Outputs) != 1 || aws.ToString(result.Outputs[0].OutputValue) != "bar-bucket" {
+		t.Fatalf("Got %v but expected the BucketName output", result.Outputs)
+	}
+
+	if result.OperationID == "" || result.ChangesetID == "" {
+		t.Fatalf("Got operation %q and changeset %q but expected both to be set", result.OperationID, result.ChangesetID)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(events) == 0 || events[len(events)-1].Type != client.EventStackStatus {
+		t.Fatalf("Got %v but expected to end with a %s event", events, client.EventStackStatus)
+	}
+}
+
+func fakeOptions(cf *fake.CloudFormation, overrides ...string) apply.Options {
+	return apply.Options{
+		Config: client.Config{
+			StackName:      "bar",
+			Region:         "us-west-2",
+			Timeout:        5 * time.Second,
+			PollInterval:   time.Millisecond,
+			CloudFormation: cf,
+			Events:         client.FuncSink(func(client.Event) {}),
+		},
+		Template: template.Input{
+			TemplatePath:   "../template/testdata/s3.yaml",
+			ParameterPaths: []string{"../template/testdata/s3-values.json"},
+			Overrides:      overrides,
+		},
+	}
+}
+
+func TestApplyScenarios(t *testing.T) {
+	cf := fake.New()
+	cf.SetOutputs("bar", map[string]string{"MainBucketName": "md-test-cf-1234"})
+
+	result, err := apply.Apply(context.Background(), fakeOptions(cf))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Status != types.StackStatusCreateComplete || len(result.Changes) != 2 || len(result.Outputs) != 1 {
+		t.Fatalf("Got %s with %d changes and %d outputs but expected CREATE_COMPLETE with 2 and 1", result.Status, len(result.Changes), len(result.Outputs))
+	}
+
+	// The same template and parameters again doesn't change anything
+	result, err = apply.Apply(context.Background(), fakeOptions(cf))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !result.NoChanges || cf.Calls("ExecuteChangeSet") != 1 {
+		t.Fatalf("Got NoChanges %t after %d executions but expected a no-op", result.NoChanges, cf.Calls("ExecuteChangeSet"))
+	}
+
+	// Changing the dev bucket fails and the update rolls back
+	cf.FailResource("DevBucket", "Bucket name already taken")
+
+	result, err = apply.Apply(context.Background(), fakeOptions(cf, "DevBucketName=taken"))
+
+	var statusErr *client.StackStatusError
+	if !errors.As(err, &statusErr) {
+		t.Fatalf("Got %v but expected a StackStatusError", err)
+	}
+
+	if result.Status != types.StackStatusUpdateRollbackComplete {
+		t.Fatalf("Got %s but expected %s", result.Status, types.StackStatusUpdateRollbackComplete)
+	}
+
+	if len(result.Failures) != 1 || result.Failures[0].LogicalID != "DevBucket" || result.Failures[0].Reason != "Bucket name already taken" {
+		t.Fatalf("Got %+v but expected the DevBucket failure", result.Failures)
+	}
+}
+
+func TestApplyRegistersSecrets(t *testing.T) {
+	redactor := redact.New()
+
+	opts := fakeOptions(fake.New(), "connections.auth=hunter22")
+	opts.Redactor = redactor
+
+	if _, err := apply.Apply(context.Background(), opts); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := redactor.String("auth is hunter22"); got != "auth is "+redact.Mask {
+		t.Fatalf("Got %s but expected the NoEcho value to be redacted", got)
+	}
+
+	// Reading the template leaves the default redactor alone
+	if got := redact.String("auth is hunter22"); got != "auth is hunter22" {
+		t.Fatalf("Got %s but expected the default redactor to be untouched", got)
+	}
+}
+
+func TestApplyRedactsEvents(t *testing.T) {
+	cf := fake.New()
+	cf.FailResource("MainBucket", "Password hunter22 is not allowed")
+
+	var mu sync.Mutex
+	events := []client.Event{}
+
+	opts := fakeOptions(cf, "connections.auth=hunter22")
+	opts.Events = client.FuncSink(func(event client.Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event)
+	})
+
+	if _, err := apply.Apply(context.Background(), opts); err == nil {
+		t.Fatal("Expected the apply to fail")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	redacted := false
+	for _, event := range events {
+		if strings.Contains(event.Reason, "hunter22") {
+			t.Fatalf("Got %s but expected the NoEcho value to be redacted", event.Reason)
+		}
+		if event.Reason == "Password "+redact.Mask+" is not allowed" {
+			redacted = true
+		}
+	}
+
+	if !redacted {
+		t.Fatalf("Got %+v but expected the redacted failure reason", events)
+	}
+}
+
+func TestApplyCreateRollsBack(t *testing.T) {
+	cf := fake.New()
+	cf.FailResource("MainBucket", "Access Denied")
+
+	result, err := apply.Apply(context.Background(), fakeOptions(cf))
+	if err == nil {
+		t.Fatal("Got nil but expected an error")
+	}
+
+	if result.Status != types.StackStatusRollbackComplete {
+		t.Fatalf("Got %s but expected %s", result.Status, types.StackStatusRollbackComplete)
+	}
+
+	stack, ok := cf.Stack("bar")
+	if !ok || stack.StackStatus != types.StackStatusRollbackComplete {
+		t.Fatalf("Got %v but expected the stack to be left in %s", stack.StackStatus, types.StackStatusRollbackComplete)
+	}
+}
+
+func TestApplyFailedChangeset(t *testing.T) {
+	cf := fake.New()
+
+	if _, err := apply.Apply(context.Background(), fakeOptions(cf)); err != nil {
+		t.Fatal(err)
+	}
+
+	// A changeset without changes is a no-op and is deleted when asked to
+	opts := fakeOptions(cf)
+	opts.DeleteEmptyChangeset = true
+
+	result, err := apply.Apply(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !result.NoChanges || cf.Calls("DeleteChangeSet") != 1 {
+		t.Fatalf("Got NoChanges %t after %d deletes but expected the empty changeset to be deleted", result.NoChanges, cf.Calls("DeleteChangeSet"))
+	}
+
+	// Any other failure is an error with CloudFormation's reason
+	cf.FailChangeSet("Parameter 'DevBucketName' must match pattern [a-z-]+")
+
+	_, err = apply.Apply(context.Background(), fakeOptions(cf, "DevBucketName=Invalid_Name"))
+
+	var changeSetErr *client.ChangeSetFailedError
+	if !errors.As(err, &changeSetErr) {
+		t.Fatalf("Got %v but expected a ChangeSetFailedError", err)
+	}
+
+	if changeSetErr.Reason != "Parameter 'DevBucketName' must match pattern [a-z-]+" || cf.Calls("ExecuteChangeSet") != 1 {
+		t.Fatalf("Got %q after %d executions but expected the failure reason without executing", changeSetErr.Reason, cf.Calls("ExecuteChangeSet"))
+	}
+}
+
+func TestApplyBusyDoesntPrune(t *testing.T) {
+	ctx := context.Background()
+	cf := fake.New()
+
+	if _, err := apply.Apply(ctx, fakeOptions(cf)); err != nil {
+		t.Fatal(err)
+	}
+
+	// A pending plan, then another pipeline's update that leaves it obsolete
+	planOpts := func(overrides ...string) plan.Options {
+		opts := fakeOptions(cf, overrides...)
+		return plan.Options{Config: opts.Config, Template: opts.Template}
+	}
+
+	if _, err := plan.Plan(ctx, planOpts("DevBucketName=pending")); err != nil {
+		t.Fatal(err)
+	}
+
+	other, err := plan.Plan(ctx, planOpts("DevBucketName=other"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = cf.ExecuteChangeSet(ctx, &cloudformation.ExecuteChangeSetInput{ChangeSetName: aws.String(other.ChangesetID), ClientRequestToken: aws.String("other-pipeline")}); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := fakeOptions(cf)
+	opts.Prune = &client.PrunePolicy{All: true}
+
+	result, err := apply.Apply(ctx, opts)
+
+	var busy *client.StackBusyError
+	if !errors.As(err, &busy) {
+		t.Fatalf("Got %v but expected a StackBusyError", err)
+	}
+
+	if len(result.Pruned) != 0 || cf.Calls("DeleteChangeSet") != 0 {
+		t.Fatalf("Got %v pruned after %d deletes but expected nothing to be pruned", result.Pruned, cf.Calls("DeleteChangeSet"))
+	}
+}
+
+func TestApplyRecoversStack(t *testing.T) {
+	t.Run("recreate failed", func(t *testing.T) {
+		cf := fake.New()
+		cf.FailResource("MainBucket", "Access Denied")
+
+		if _, err := apply.Apply(context.Background(), fakeOptions(cf)); err == nil {
+			t.Fatal("Got nil but expected the create to fail")
+		}
+
+		_, err := apply.Apply(context.Background(), fakeOptions(cf))
+
+		var notUpdatable *client.NotUpdatableError
+		if !errors.As(err, &notUpdatable) || notUpdatable.Status != types.StackStatusRollbackComplete {
+			t.Fatalf("Got %v but expected a NotUpdatableError for %s", err, types.StackStatusRollbackComplete)
+		}
+
+		// A parameter mistake is caught before the stack is deleted
+		opts := fakeOptions(cf, "BucketNmae=typo")
+		opts.RecreateFailed = true
+
+		_, err = apply.Apply(context.Background(), opts)
+
+		var parameterErr *template.ParameterError
+		if !errors.As(err, &parameterErr) || cf.Calls("DeleteStack") != 0 {
+			t.Fatalf("Got %v after %d deletes but expected a ParameterError without deleting the stack", err, cf.Calls("DeleteStack"))
+		}
+
+		opts = fakeOptions(cf)
+		opts.RecreateFailed = true
+
+		result, err := apply.Apply(context.Background(), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if result.Status != types.StackStatusCreateComplete || cf.Calls("DeleteStack") != 1 {
+			t.Fatalf("Got %s after %d deletes but expected the stack to be recreated", result.Status, cf.Calls("DeleteStack"))
+		}
+	})
+
+	t.Run("continue rollback", func(t *testing.T) {
+		cf := fake.New()
+
+		if _, err := apply.Apply(context.Background(), fakeOptions(cf)); err != nil {
+			t.Fatal(err)
+		}
+
+		cf.FailResource("DevBucket", "Bucket name already taken")
+		cf.FailRollback()
+
+		result, _ := apply.Apply(context.Background(), fakeOptions(cf, "DevBucketName=taken"))
+		if result.Status != types.StackStatusUpdateRollbackFailed {
+			t.Fatalf("Got %s but expected %s", result.Status, types.StackStatusUpdateRollbackFailed)
+		}
+
+		opts := fakeOptions(cf, "DevBucketName=taken")
+		opts.ContinueRollback = true
+		opts.SkipResources = []string{"DevBucket"}
+
+		result, err := apply.Apply(context.Background(), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if result.Status != types.StackStatusUpdateComplete || cf.Calls("ContinueUpdateRollback") != 1 {
+			t.Fatalf("Got %s after %d continues but expected the rollback to be continued and the update applied", result.Status, cf.Calls("ContinueUpdateRollback"))
+		}
+	})
+	t.Run("continue rollback scopes failures", func(t *testing.T) {
+		cf := fake.New()
+
+		if _, err := apply.Apply(context.Background(), fakeOptions(cf)); err != nil {
+			t.Fatal(err)
+		}
+
+		cf.FailResource("DevBucket", "Bucket name already taken")
+		cf.FailRollback()
+
+		if _, err := apply.Apply(context.Background(), fakeOptions(cf, "DevBucketName=taken")); err == nil {
+			t.Fatal("Got nil but expected the update to fail")
+		}
+
+		// The continued rollback reports a failure of its own, then the update fails again
+		cf.FailCleanup("DevBucket", "The bucket you tried to delete is not empty")
+		cf.FailResource("DevBucket", "Bucket name already taken")
+
+		opts := fakeOptions(cf, "DevBucketName=taken")
+		opts.ContinueRollback = true
+		opts.SkipResources = []string{"DevBucket"}
+
+		result, err := apply.Apply(context.Background(), opts)
+
+		var statusErr *client.StackStatusError
+		if !errors.As(err, &statusErr) {
+			t.Fatalf("Got %v but expected a StackStatusError", err)
+		}
+
+		if len(result.Failures) != 1 || result.Failures[0].Reason != "Bucket name already taken" {
+			t.Fatalf("Got %+v but expected only the failure of the update, not the rollback's", result.Failures)
+		}
+	})
+}
@@ -0,0 +1,117 @@
+package changesets
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/massdriver-cloud/fogmachine/pkg/plan"
+)
+
+// Changeset is the rendered form of a changeset, Changes is only set when it's described
+type Changeset struct {
+	ID              string                `json:"id"`
+	Name            string                `json:"name"`
+	Status          string                `json:"status"`
+	ExecutionStatus string                `json:"executionStatus"`
+	Reason          string                `json:"reason,omitempty"`
+	Description     string                `json:"description,omitempty"`
+	Created         time.Time             `json:"created"`
+	Changes         []plan.ResourceChange `json:"changes,omitempty"`
+}
+
+func fromSummary(summary types.ChangeSetSummary) Changeset {
+	return Changeset{
+		ID:              aws.ToString(summary.ChangeSetId),
+		Name:            aws.ToString(summary.ChangeSetName),
+		Status:          string(summary.Status),
+		ExecutionStatus: string(summary.ExecutionStatus),
+		Reason:          aws.ToString(summary.StatusReason),
+		Description:     aws.ToString(summary.Description),
+		Created:         aws.ToTime(summary.CreationTime),
+	}
+}
+
+// RenderList writes the changesets to w in the requested format
+func RenderList(w io.Writer, summaries []types.ChangeSetSummary, format string) error {
+	rendered := make([]Changeset, 0, len(summaries))
+	for _, summary := range summaries {
+		rendered = append(rendered, fromSummary(summary))
+	}
+
+	switch format {
+	case plan.FormatJSON:
+		return renderJSON(w, rendered)
+	case plan.FormatTable, "":
+		if len(rendered) == 0 {
+			_, err := fmt.Fprintln(w, "No changesets")
+			return err
+		}
+
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "NAME\tSTATUS\tEXECUTION\tCREATED\tREASON")
+		for _, changeset := range rendered {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n",
+				changeset.Name,
+				changeset.Status,
+				changeset.ExecutionStatus,
+				changeset.Created.UTC().Format(time.RFC3339),
+				orDash(changeset.Reason),
+			)
+		}
+		return tw.Flush()
+	default:
+		return fmt.Errorf("unknown format %q, expected one of [%s, %s]", format, plan.FormatTable, plan.FormatJSON)
+	}
+}
+
+// RenderDescribe writes the changeset and its changes to w in the requested format
+func RenderDescribe(w io.Writer, described *cloudformation.DescribeChangeSetOutput, format string) error {
+	changeset := Changeset{
+		ID:              aws.ToString(described.ChangeSetId),
+		Name:            aws.ToString(described.ChangeSetName),
+		Status:          string(described.Status),
+		ExecutionStatus: string(described.ExecutionStatus),
+		Reason:          aws.ToString(described.StatusReason),
+		Description:     aws.ToString(described.Description),
+		Created:         aws.ToTime(described.CreationTime),
+		Changes:         plan.ResourceChanges(described.Changes),
+	}
+
+	switch format {
+	case plan.FormatJSON:
+		return renderJSON(w, changeset)
+	case plan.FormatTable, "":
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(tw, "Name:\t%s\n", changeset.Name)
+		fmt.Fprintf(tw, "ID:\t%s\n", changeset.ID)
+		fmt.Fprintf(tw, "Status:\t%s\n", changeset.Status)
+		fmt.Fprintf(tw, "Execution:\t%s\n", changeset.ExecutionStatus)
+		fmt.Fprintf(tw, "Created:\t%s\n", changeset.Created.UTC().Format(time.RFC3339))
+		fmt.Fprintf(tw, "Reason:\t%s\n\n", orDash(changeset.Reason))
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+		return plan.Render(w, described.Changes, plan.FormatTable)
+	default:
+		return fmt.Errorf("unknown format %q, expected one of [%s, %s]", format, plan.FormatTable, plan.FormatJSON)
+	}
+}
+
+func renderJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
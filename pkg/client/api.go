@@ -0,0 +1,101 @@
+package client
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+)
+
+// CloudFormationAPI is the part of the CloudFormation API the client uses. *cloudformation.Client implements it,
+// as does the in-memory fake in pkg/testing/fake.
+type CloudFormationAPI interface {
+	ContinueUpdateRollback(ctx context.Context, params *cloudformation.ContinueUpdateRollbackInput, optFns ...func(*cloudformation.Options)) (*cloudformation.ContinueUpdateRollbackOutput, error)
+	CreateChangeSet(ctx context.Context, params *cloudformation.CreateChangeSetInput, optFns ...func(*cloudformation.Options)) (*cloudformation.CreateChangeSetOutput, error)
+	DeleteChangeSet(ctx context.Context, params *cloudformation.DeleteChangeSetInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DeleteChangeSetOutput, error)
+	DeleteStack(ctx context.Context, params *cloudformation.DeleteStackInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DeleteStackOutput, error)
+	DescribeChangeSet(ctx context.Context, params *cloudformation.DescribeChangeSetInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DescribeChangeSetOutput, error)
+	DescribeStackEvents(ctx context.Context, params *cloudformation.DescribeStackEventsInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DescribeStackEventsOutput, error)
+	DescribeStacks(ctx context.Context, params *cloudformation.DescribeStacksInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DescribeStacksOutput, error)
+	ExecuteChangeSet(ctx context.Context, params *cloudformation.ExecuteChangeSetInput, optFns ...func(*cloudformation.Options)) (*cloudformation.ExecuteChangeSetOutput, error)
+	ListChangeSets(ctx context.Context, params *cloudformation.ListChangeSetsInput, optFns ...func(*cloudformation.Options)) (*cloudformation.ListChangeSetsOutput, error)
+	SetStackPolicy(ctx context.Context, params *cloudformation.SetStackPolicyInput, optFns ...func(*cloudformation.Options)) (*cloudformation.SetStackPolicyOutput, error)
+	ValidateTemplate(ctx context.Context, params *cloudformation.ValidateTemplateInput, optFns ...func(*cloudformation.Options)) (*cloudformation.ValidateTemplateOutput, error)
+}
+
+// The mock generator reads the CloudFormation methods this package references, since the client only calls
+// them through CloudFormationAPI every method of the interface is listed here
+var _ = []interface{}{
+	(*cloudformation.Client).ContinueUpdateRollback,
+	(*cloudformation.Client).CreateChangeSet,
+	(*cloudformation.Client).DeleteChangeSet,
+	(*cloudformation.Client).DeleteStack,
+	(*cloudformation.Client).DescribeChangeSet,
+	(*cloudformation.Client).DescribeStackEvents,
+	(*cloudformation.Client).DescribeStacks,
+	(*cloudformation.Client).ExecuteChangeSet,
+	(*cloudformation.Client).ListChangeSets,
+	(*cloudformation.Client).SetStackPolicy,
+	(*cloudformation.Client).ValidateTemplate,
+}
+
+var _ CloudFormationAPI = (*cloudformation.Client)(nil)
+
+// classifiedAPI returns the errors of the API it wraps as the typed errors in errors.go
+type classifiedAPI struct {
+	api CloudFormationAPI
+}
+
+func (a classifiedAPI) ContinueUpdateRollback(ctx context.Context, params *cloudformation.ContinueUpdateRollbackInput, optFns ...func(*cloudformation.Options)) (*cloudformation.ContinueUpdateRollbackOutput, error) {
+	output, err := a.api.ContinueUpdateRollback(ctx, params, optFns...)
+	return output, classify(err)
+}
+
+func (a classifiedAPI) CreateChangeSet(ctx context.Context, params *cloudformation.CreateChangeSetInput, optFns ...func(*cloudformation.Options)) (*cloudformation.CreateChangeSetOutput, error) {
+	output, err := a.api.CreateChangeSet(ctx, params, optFns...)
+	return output, classify(err)
+}
+
+func (a classifiedAPI) DeleteChangeSet(ctx context.Context, params *cloudformation.DeleteChangeSetInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DeleteChangeSetOutput, error) {
+	output, err := a.api.DeleteChangeSet(ctx, params, optFns...)
+	return output, classify(err)
+}
+
+func (a classifiedAPI) DeleteStack(ctx context.Context, params *cloudformation.DeleteStackInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DeleteStackOutput, error) {
+	output, err := a.api.DeleteStack(ctx, params, optFns...)
+	return output, classify(err)
+}
+
+func (a classifiedAPI) DescribeChangeSet(ctx context.Context, params *cloudformation.DescribeChangeSetInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DescribeChangeSetOutput, error) {
+	output, err := a.api.DescribeChangeSet(ctx, params, optFns...)
+	return output, classify(err)
+}
+
+func (a classifiedAPI) DescribeStackEvents(ctx context.Context, params *cloudformation.DescribeStackEventsInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DescribeStackEventsOutput, error) {
+	output, err := a.api.DescribeStackEvents(ctx, params, optFns...)
+	return output, classify(err)
+}
+
+func (a classifiedAPI) DescribeStacks(ctx context.Context, params *cloudformation.DescribeStacksInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DescribeStacksOutput, error) {
+	output, err := a.api.DescribeStacks(ctx, params, optFns...)
+	return output, classify(err)
+}
+
+func (a classifiedAPI) ExecuteChangeSet(ctx context.Context, params *cloudformation.ExecuteChangeSetInput, optFns ...func(*cloudformation.Options)) (*cloudformation.ExecuteChangeSetOutput, error) {
+	output, err := a.api.ExecuteChangeSet(ctx, params, optFns...)
+	return output, classify(err)
+}
+
+func (a classifiedAPI) ListChangeSets(ctx context.Context, params *cloudformation.ListChangeSetsInput, optFns ...func(*cloudformation.Options)) (*cloudformation.ListChangeSetsOutput, error) {
+	output, err := a.api.ListChangeSets(ctx, params, optFns...)
+	return output, classify(err)
+}
+
+func (a classifiedAPI) SetStackPolicy(ctx context.Context, params *cloudformation.SetStackPolicyInput, optFns ...func(*cloudformation.Options)) (*cloudformation.SetStackPolicyOutput, error) {
+	output, err := a.api.SetStackPolicy(ctx, params, optFns...)
+	return output, classify(err)
+}
+
+func (a classifiedAPI) ValidateTemplate(ctx context.Context, params *cloudformation.ValidateTemplateInput, optFns ...func(*cloudformation.Options)) (*cloudformation.ValidateTemplateOutput, error) {
+	output, err := a.api.ValidateTemplate(ctx, params, optFns...)
+	return output, classify(err)
+}
@@ -0,0 +1,89 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/rs/zerolog/log"
+)
+
+// BusyPolicy is what the client does when another operation is already running on the stack
+type BusyPolicy string
+
+const (
+	// BusyFail returns a StackBusyError straight away
+	BusyFail BusyPolicy = "fail"
+	// BusyWait follows the other operation until it finishes or the busy timeout passes
+	BusyWait BusyPolicy = "wait"
+)
+
+// StackBusyError is returned when another operation is running on the stack, Err is ErrTimeout when it didn't
+// finish in time
+type StackBusyError struct {
+	Status types.StackStatus
+	Err    error
+}
+
+func (e *StackBusyError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("stack is busy in %s: %s", e.Status, e.Err)
+	}
+	return fmt.Sprintf("stack is busy in %s, another operation is running on it", e.Status)
+}
+
+func (e *StackBusyError) Unwrap() error {
+	return e.Err
+}
+
+// Hint suggests how to fix the error
+func (e *StackBusyError) Hint() string {
+	if e.Err != nil {
+		return "the other operation is still running, raise --busy-timeout or try again once it finishes"
+	}
+	return "wait for the other operation to finish, or use --on-busy wait to wait for it"
+}
+
+// WaitUntilIdle returns once no other operation is running on the stack. With BusyFail it returns a StackBusyError
+// when one is, with BusyWait it emits the other operation's events until it finishes or the busy timeout passes.
+func (c Client) WaitUntilIdle(ctx context.Context) error {
+	status, err := c.StackStatus(ctx)
+	if err != nil || !busy(status) {
+		return err
+	}
+
+	if c.onBusy != BusyWait {
+		return &StackBusyError{Status: status}
+	}
+
+	log.Info().Str("phase", "Execution").Str("status", string(status)).Msg("Waiting for the running operation to finish")
+
+	tracker := newEventTracker(c.stackID, "", time.Now())
+	deadline := time.Now().Add(c.busyTimeout)
+
+	for busy(status) {
+		if time.Now().After(deadline) {
+			return &StackBusyError{Status: status, Err: ErrTimeout}
+		}
+
+		time.Sleep(c.pollIntervel)
+
+		if err = c.pollStackEvents(ctx, tracker); err != nil && !isNotFound(err) {
+			return err
+		}
+
+		if status, err = c.StackStatus(ctx); err != nil {
+			return err
+		}
+	}
+
+	log.Info().Str("phase", "Execution").Str("status", string(status)).Msg("Running operation finished")
+
+	return nil
+}
+
+// busy reports whether an operation is running on a stack in the status, a stack in review only holds changesets
+func busy(status types.StackStatus) bool {
+	return status != "" && status != types.StackStatusReviewInProgress && CategorizeStackStatus(status) == StatusInProgress
+}
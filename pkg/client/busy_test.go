@@ -0,0 +1,95 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/massdriver-cloud/fogmachine/pkg/client"
+	"github.com/massdriver-cloud/fogmachine/pkg/testing/fake"
+)
+
+// startUpdate starts another pipeline's update of the stack directly on the fake
+func startUpdate(t *testing.T, cf *fake.CloudFormation) {
+	ctx := context.Background()
+
+	created, err := cf.CreateChangeSet(ctx, &cloudformation.CreateChangeSetInput{
+		StackName:     aws.String("bar"),
+		ChangeSetName: aws.String("other-pipeline"),
+		ChangeSetType: types.ChangeSetTypeUpdate,
+		TemplateBody:  aws.String(bucketTemplate + "  Logs:\n    Type: AWS::S3::Bucket\n"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err = cf.DescribeChangeSet(ctx, &cloudformation.DescribeChangeSetInput{ChangeSetName: created.Id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err = cf.ExecuteChangeSet(ctx, &cloudformation.ExecuteChangeSetInput{ChangeSetName: created.Id, ClientRequestToken: aws.String("other-pipeline")}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWaitUntilIdle(t *testing.T) {
+	tests := map[string]struct {
+		onBusy      client.BusyPolicy
+		busyTimeout time.Duration
+		wantErr     error
+	}{
+		"fail":    {onBusy: client.BusyFail},
+		"wait":    {onBusy: client.BusyWait, busyTimeout: 5 * time.Second},
+		"timeout": {onBusy: client.BusyWait, busyTimeout: time.Nanosecond, wantErr: client.ErrTimeout},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			cf := fake.New()
+			if err := cf.AddStack("bar", types.StackStatusCreateComplete, bucketTemplate); err != nil {
+				t.Fatal(err)
+			}
+			startUpdate(t, cf)
+
+			events := []client.Event{}
+			c, err := client.New(context.Background(), client.Config{
+				StackName:      "bar",
+				PollInterval:   time.Millisecond,
+				OnBusy:         tc.onBusy,
+				BusyTimeout:    tc.busyTimeout,
+				CloudFormation: cf,
+				Events:         client.FuncSink(func(event client.Event) { events = append(events, event) }),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = c.WaitUntilIdle(context.Background())
+
+			if tc.onBusy == client.BusyWait && tc.wantErr == nil {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if stack, _ := cf.Stack("bar"); stack.StackStatus != types.StackStatusUpdateComplete || len(events) == 0 {
+					t.Fatalf("Got %s with %d events but expected to follow the update to %s", stack.StackStatus, len(events), types.StackStatusUpdateComplete)
+				}
+				return
+			}
+
+			var busyErr *client.StackBusyError
+			if !errors.As(err, &busyErr) || busyErr.Status != types.StackStatusUpdateInProgress {
+				t.Fatalf("Got %v but expected a StackBusyError in %s", err, types.StackStatusUpdateInProgress)
+			}
+
+			if !errors.Is(err, tc.wantErr) && tc.wantErr != nil {
+				t.Fatalf("Got %v but expected %v", err, tc.wantErr)
+			}
+		})
+	}
+}
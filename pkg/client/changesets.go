@@ -0,0 +1,153 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultPruneGracePeriod is how long the prune commands keep changesets that can still be executed
+const DefaultPruneGracePeriod = 24 * time.Hour
+
+// PrunePolicy decides which of a stack's changesets PruneChangeSets deletes. Changesets that are executing or
+// were executed are never deleted.
+type PrunePolicy struct {
+	// Keep is how many of the newest changesets are kept, the older ones are deleted. A negative Keep keeps them all.
+	Keep int
+	// Failed deletes every FAILED changeset, including ones Keep would keep
+	Failed bool
+	// GracePeriod keeps changesets that can still be executed, such as pending plans, until they're this old
+	GracePeriod time.Duration
+	// All prunes changesets fogmachine didn't create too, and executable ones within the grace period
+	All bool
+}
+
+// ChangeSets returns the changesets of the stack, newest first
+func (c Client) ChangeSets(ctx context.Context) ([]types.ChangeSetSummary, error) {
+	params := &cloudformation.ListChangeSetsInput{
+		StackName: aws.String(c.stackID),
+	}
+
+	summaries := []types.ChangeSetSummary{}
+
+	for {
+		result, err := c.client.ListChangeSets(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+
+		summaries = append(summaries, result.Summaries...)
+
+		if result.NextToken == nil {
+			break
+		}
+
+		params.NextToken = result.NextToken
+	}
+
+	sort.SliceStable(summaries, func(i, j int) bool {
+		return aws.ToTime(summaries[i].CreationTime).After(aws.ToTime(summaries[j].CreationTime))
+	})
+
+	return summaries, nil
+}
+
+// DescribeChangeSet describes a changeset of the stack by name or ID, with every change
+func (c Client) DescribeChangeSet(ctx context.Context, nameOrID string) (*cloudformation.DescribeChangeSetOutput, error) {
+	params := &cloudformation.DescribeChangeSetInput{
+		ChangeSetName: aws.String(nameOrID),
+		StackName:     aws.String(c.stackID),
+	}
+
+	described, err := c.client.DescribeChangeSet(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	for next := described.NextToken; next != nil; {
+		params.NextToken = next
+
+		result, err := c.client.DescribeChangeSet(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+
+		described.Changes = append(described.Changes, result.Changes...)
+		next = result.NextToken
+	}
+
+	described.NextToken = nil
+
+	return described, nil
+}
+
+// DeleteChangeSet deletes a changeset of the stack by name or ID
+func (c Client) DeleteChangeSet(ctx context.Context, nameOrID string) error {
+	_, err := c.client.DeleteChangeSet(ctx, &cloudformation.DeleteChangeSetInput{
+		ChangeSetName: aws.String(nameOrID),
+		StackName:     aws.String(c.stackID),
+	})
+	return err
+}
+
+// PruneChangeSets deletes the changesets of the stack the policy doesn't keep and returns them
+func (c Client) PruneChangeSets(ctx context.Context, policy PrunePolicy) ([]types.ChangeSetSummary, error) {
+	summaries, err := c.ChangeSets(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pruned := []types.ChangeSetSummary{}
+	for _, summary := range policy.prunable(summaries, time.Now()) {
+		if err = c.DeleteChangeSet(ctx, aws.ToString(summary.ChangeSetId)); err != nil {
+			return pruned, fmt.Errorf("unable to delete changeset %s: %w", aws.ToString(summary.ChangeSetName), err)
+		}
+
+		log.Info().Str("phase", "Changeset").Str("changesetId", aws.ToString(summary.ChangeSetId)).Msg("Pruned changeset")
+		pruned = append(pruned, summary)
+	}
+
+	return pruned, nil
+}
+
+// prunable returns the changesets the policy deletes from summaries, which are newest first
+func (p PrunePolicy) prunable(summaries []types.ChangeSetSummary, now time.Time) []types.ChangeSetSummary {
+	prunable := []types.ChangeSetSummary{}
+	kept := 0
+
+	for _, summary := range summaries {
+		if summary.ExecutionStatus == types.ExecutionStatusExecuteInProgress || summary.ExecutionStatus == types.ExecutionStatusExecuteComplete {
+			continue
+		}
+
+		if !p.All && aws.ToString(summary.Description) != ChangesetDescription {
+			continue
+		}
+
+		switch {
+		case !p.All && p.withinGracePeriod(summary, now):
+			kept++
+		case p.Failed && summary.Status == types.ChangeSetStatusFailed:
+			prunable = append(prunable, summary)
+		case p.Keep >= 0 && kept >= p.Keep:
+			prunable = append(prunable, summary)
+		default:
+			kept++
+		}
+	}
+
+	return prunable
+}
+
+// withinGracePeriod reports whether the changeset can still be executed and is newer than the grace period
+func (p PrunePolicy) withinGracePeriod(summary types.ChangeSetSummary, now time.Time) bool {
+	return summary.Status == types.ChangeSetStatusCreateComplete &&
+		summary.ExecutionStatus == types.ExecutionStatusAvailable &&
+		now.Sub(aws.ToTime(summary.CreationTime)) < p.GracePeriod
+}
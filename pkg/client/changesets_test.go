@@ -0,0 +1,123 @@
+package client_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/massdriver-cloud/fogmachine/pkg/client"
+	"github.com/massdriver-cloud/fogmachine/pkg/testing/fake"
+)
+
+func TestPruneChangeSets(t *testing.T) {
+	ctx := context.Background()
+
+	cf := fake.New()
+	tick := 0
+	cf.Now = func() time.Time {
+		tick++
+		return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC).Add(time.Duration(tick) * time.Second)
+	}
+
+	if err := cf.AddStack("bar", types.StackStatusCreateComplete, bucketTemplate); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := client.New(ctx, client.Config{StackName: "bar", PollInterval: time.Millisecond, CloudFormation: cf, Events: client.FuncSink(func(client.Event) {})})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	twoBuckets := bucketTemplate + "  Logs:\n    Type: AWS::S3::Bucket\n"
+
+	// Two no-op changesets, one that failed and one that can be executed
+	_ = c.CreateChangeset(ctx, []byte(bucketTemplate), nil, client.StackOptions{})
+	_ = c.CreateChangeset(ctx, []byte(bucketTemplate), nil, client.StackOptions{})
+	cf.FailChangeSet("Template error: unresolved resource dependencies")
+	_ = c.CreateChangeset(ctx, []byte(twoBuckets), nil, client.StackOptions{})
+	if err = c.CreateChangeset(ctx, []byte(twoBuckets), nil, client.StackOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// One created by someone else
+	_, err = cf.CreateChangeSet(ctx, &cloudformation.CreateChangeSetInput{
+		StackName:     aws.String("bar"),
+		ChangeSetName: aws.String("console"),
+		ChangeSetType: types.ChangeSetTypeUpdate,
+		TemplateBody:  aws.String(twoBuckets),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	summaries, err := c.ChangeSets(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(summaries) != 5 || aws.ToString(summaries[0].ChangeSetName) != "console" {
+		t.Fatalf("Got %d changesets but expected 5 starting with console", len(summaries))
+	}
+
+	pruned, err := c.PruneChangeSets(ctx, client.PrunePolicy{Keep: -1, Failed: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(pruned) != 3 {
+		t.Fatalf("Got %d but expected the 3 failed changesets to be pruned", len(pruned))
+	}
+
+	pruned, err = c.PruneChangeSets(ctx, client.PrunePolicy{Keep: 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(pruned) != 1 || aws.ToString(pruned[0].ChangeSetId) != c.ChangesetID() {
+		t.Fatalf("Got %d but expected only the executable changeset to be pruned", len(pruned))
+	}
+
+	if summaries, _ = c.ChangeSets(ctx); len(summaries) != 1 || aws.ToString(summaries[0].ChangeSetName) != "console" {
+		t.Fatalf("Got %d changesets but expected only console to be left", len(summaries))
+	}
+}
+
+func TestPruneChangeSetsGracePeriod(t *testing.T) {
+	ctx := context.Background()
+
+	cf := fake.New()
+	if err := cf.AddStack("bar", types.StackStatusCreateComplete, bucketTemplate); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := client.New(ctx, client.Config{StackName: "bar", PollInterval: time.Millisecond, CloudFormation: cf, Events: client.FuncSink(func(client.Event) {})})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A pending plan that can still be executed
+	if err = c.CreateChangeset(ctx, []byte(bucketTemplate+"  Logs:\n    Type: AWS::S3::Bucket\n"), nil, client.StackOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	pruned, err := c.PruneChangeSets(ctx, client.PrunePolicy{Keep: 0, GracePeriod: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(pruned) != 0 {
+		t.Fatalf("Got %d but expected the changeset within the grace period to be kept", len(pruned))
+	}
+
+	pruned, err = c.PruneChangeSets(ctx, client.PrunePolicy{Keep: 0, GracePeriod: time.Hour, All: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(pruned) != 1 || aws.ToString(pruned[0].ChangeSetId) != c.ChangesetID() {
+		t.Fatalf("Got %d but expected --all to prune the changeset within the grace period", len(pruned))
+	}
+}
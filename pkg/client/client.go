@@ -4,27 +4,99 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
 	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
-	"github.com/massdriver-cloud/fogmachine/pkg/eventcache"
+	"github.com/massdriver-cloud/fogmachine/pkg/redact"
 	"github.com/rs/zerolog/log"
 	"golang.org/x/sync/errgroup"
 )
 
 //go:generate go run ../../generate/main.go
 
+// ChangesetDescription is the description of every changeset fogmachine creates
+const ChangesetDescription = "Changeset created via Fog-Machine"
+
 type Client struct {
-	client       *cloudformation.Client
-	eventCache   *eventcache.EventCache
+	client       CloudFormationAPI
 	stackID      string
 	changesetID  *string
 	pollIntervel time.Duration
 	timeout      time.Duration
+	operationID  string
+	sink         EventSink
+	redactor     *redact.Redactor
+	// deleteEmptyChangeset deletes a changeset that failed for having no changes
+	deleteEmptyChangeset bool
+	onBusy               BusyPolicy
+	busyTimeout          time.Duration
+}
+
+// Config configures a client created with New
+type Config struct {
+	StackName string
+	Region    string
+	// Timeout is how long to wait for the stack to finish, 10 minutes by default
+	Timeout time.Duration
+	// PollInterval is the time between polls of the CloudFormation API, 3 seconds by default
+	PollInterval time.Duration
+	// Events receives every event of the operation, they're logged when it's nil. The client doesn't close it.
+	Events EventSink
+	// Redactor masks secrets in every event before it reaches Events. A redactor of the client's own, with only
+	// the built in patterns, is used when it's nil.
+	Redactor *redact.Redactor
+	// OnBusy is what WaitUntilIdle does when another operation is running on the stack, BusyFail by default
+	OnBusy BusyPolicy
+	// BusyTimeout is how long BusyWait waits for the other operation, Timeout by default
+	BusyTimeout time.Duration
+	// DeleteEmptyChangeset deletes a changeset that failed for having no changes instead of leaving it on the stack
+	DeleteEmptyChangeset bool
+	// CloudFormation is the API client to use, one is created for Region from the default AWS config when it's nil
+	CloudFormation CloudFormationAPI
+}
+
+// New creates a client from the config
+func New(ctx context.Context, cfg Config) (*Client, error) {
+	api := cfg.CloudFormation
+	if api == nil {
+		awsConfig, err := config.LoadDefaultConfig(ctx, config.WithRegion(cfg.Region))
+		if err != nil {
+			return nil, err
+		}
+		api = cloudformation.NewFromConfig(awsConfig)
+	}
+
+	c := &Client{
+		client:       classifiedAPI{api: api},
+		stackID:      cfg.StackName,
+		pollIntervel: cfg.PollInterval,
+		timeout:      cfg.Timeout,
+		operationID:  newOperationToken(),
+		redactor:     cfg.Redactor,
+
+		deleteEmptyChangeset: cfg.DeleteEmptyChangeset,
+		onBusy:               cfg.OnBusy,
+		busyTimeout:          cfg.BusyTimeout,
+	}
+
+	if c.pollIntervel <= 0 {
+		c.pollIntervel = 3 * time.Second
+	}
+	if c.timeout <= 0 {
+		c.timeout = 10 * time.Minute
+	}
+	if c.busyTimeout <= 0 {
+		c.busyTimeout = c.timeout
+	}
+	if c.redactor == nil {
+		c.redactor = redact.New()
+	}
+	c.SetEventSink(cfg.Events)
+
+	return c, nil
 }
 
 func NewCloudformationClient(ctx context.Context, packageName, region string, t, pollInterval int) (*Client, error) {
@@ -36,37 +108,47 @@ func NewCloudformationClient(ctx context.Context, packageName, region string, t,
 	return NewCloudformationClientWithCFClient(packageName, t, pollInterval, cloudformation.NewFromConfig(cfg))
 }
 
-func NewCloudformationClientWithCFClient(packageName string, t, pollInterval int, cfClient *cloudformation.Client) (*Client, error) {
-	return &Client{
-		client:       cfClient,
-		eventCache:   eventcache.New(),
+func NewCloudformationClientWithCFClient(packageName string, t, pollInterval int, cfClient CloudFormationAPI) (*Client, error) {
+	c := &Client{
+		client:       classifiedAPI{api: cfClient},
 		stackID:      packageName,
 		pollIntervel: time.Duration(pollInterval) * time.Second,
 		timeout:      time.Duration(t) * time.Second,
-	}, nil
+		operationID:  newOperationToken(),
+		redactor:     redact.New(),
+	}
+	c.SetEventSink(nil)
+
+	return c, nil
 }
 
-func (c *Client) CreateChangeset(ctx context.Context, template []byte, parameters []types.Parameter) error {
+func (c *Client) CreateChangeset(ctx context.Context, template []byte, parameters []types.Parameter, options StackOptions) error {
+	if err := options.Validate(); err != nil {
+		return err
+	}
+
 	input := &cloudformation.CreateChangeSetInput{
 		ChangeSetName: aws.String(fmt.Sprintf("%s-%d", c.stackID, time.Now().Unix())),
 		ChangeSetType: types.ChangeSetTypeCreate,
 		StackName:     aws.String(c.stackID),
-		Description:   aws.String("Changeset created via Fog-Machine"),
+		Description:   aws.String(ChangesetDescription),
 		TemplateBody:  aws.String(string(template)),
 		Parameters:    parameters,
 	}
 
-	ok, err := c.stackExists(ctx)
+	options.applyTo(input)
+
+	status, err := c.StackStatus(ctx)
 	if err != nil {
 		return err
 	}
 
-	if ok {
+	if status != "" && !Updatable(status) {
+		return &NotUpdatableError{Status: status}
+	}
+
+	if status != "" && status != types.StackStatusReviewInProgress {
 		input.ChangeSetType = types.ChangeSetTypeUpdate
-		err = c.primeEventCache(ctx)
-		if err != nil {
-			return err
-		}
 	}
 
 	log.Info().Str("phase", "Changeset").Msg("Creating changeset")
@@ -81,43 +163,99 @@ func (c *Client) CreateChangeset(ctx context.Context, template []byte, parameter
 	return c.changeSetStatusWatcher(ctx)
 }
 
-func (c *Client) primeEventCache(ctx context.Context) error {
-	params := &cloudformation.DescribeStackEventsInput{
-		StackName: aws.String(c.stackID),
-	}
+// SetDeleteEmptyChangeset sets whether a changeset that failed for having no changes is deleted
+func (c *Client) SetDeleteEmptyChangeset(deleteEmpty bool) {
+	c.deleteEmptyChangeset = deleteEmpty
+}
+
+// ChangesetID returns the ID of the changeset created or adopted by the client
+func (c Client) ChangesetID() string {
+	return aws.ToString(c.changesetID)
+}
 
-	result, err := c.client.DescribeStackEvents(ctx, params)
+// AdoptChangeset uses an existing changeset instead of creating a new one. The changeset must belong
+// to the client's stack, be fully created and still be executable against the stack's current state.
+func (c *Client) AdoptChangeset(ctx context.Context, changesetID string) error {
+	log.Info().Str("phase", "Changeset").Str("changesetId", changesetID).Msg("Verifying changeset")
+
+	result, err := c.client.DescribeChangeSet(ctx, &cloudformation.DescribeChangeSetInput{
+		ChangeSetName: aws.String(changesetID),
+		StackName:     aws.String(c.stackID),
+	})
 	if err != nil {
-		return err
+		return fmt.Errorf("unable to find changeset %s: %w", changesetID, err)
 	}
 
-	for _, event := range result.StackEvents {
-		c.eventCache.AddEvent(*event.EventId, c.eventCache.EventFromStack(event, "Cache"))
+	if aws.ToString(result.StackName) != c.stackID {
+		return fmt.Errorf("changeset %s belongs to stack %s, not %s", changesetID, aws.ToString(result.StackName), c.stackID)
 	}
 
+	if result.Status == types.ChangeSetStatusFailed && isNoChangesReason(aws.ToString(result.StatusReason)) {
+		c.changesetID = result.ChangeSetId
+		return &NoChangesError{Reason: aws.ToString(result.StatusReason)}
+	}
+
+	if result.Status != types.ChangeSetStatusCreateComplete {
+		return fmt.Errorf("changeset %s has status %s, expected %s: %s", changesetID, result.Status, types.ChangeSetStatusCreateComplete, aws.ToString(result.StatusReason))
+	}
+
+	if result.ExecutionStatus != types.ExecutionStatusAvailable {
+		return fmt.Errorf("changeset %s has execution status %s, the stack may have changed since it was created", changesetID, result.ExecutionStatus)
+	}
+
+	c.changesetID = result.ChangeSetId
+
 	return nil
 }
 
 func (c Client) stackExists(ctx context.Context) (bool, error) {
+	status, err := c.StackStatus(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	return status != "" && status != types.StackStatusReviewInProgress, nil
+}
+
+// StackStatus returns the status of the stack, empty when it doesn't exist
+func (c Client) StackStatus(ctx context.Context) (types.StackStatus, error) {
 	params := cloudformation.DescribeStacksInput{
 		StackName: aws.String(c.stackID),
 	}
 
 	response, err := c.client.DescribeStacks(ctx, &params)
 	if err != nil {
-		if !errorIsDoesNotExist(err) {
-			return false, err
+		if !isNotFound(err) {
+			return "", err
 		}
-		return false, nil
+		return "", nil
 	}
 
 	if len(response.Stacks) != 1 {
-		return false, nil
+		return "", nil
+	}
+
+	return response.Stacks[0].StackStatus, nil
+}
+
+// StackParameters returns the parameters of the existing stack, none when the stack hasn't been created yet
+func (c Client) StackParameters(ctx context.Context) ([]types.Parameter, error) {
+	response, err := c.client.DescribeStacks(ctx, &cloudformation.DescribeStacksInput{
+		StackName: aws.String(c.stackID),
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return []types.Parameter{}, nil
+		}
+		return nil, err
 	}
 
-	inReview := response.Stacks[0].StackStatus != "REVIEW_IN_PROGRESS"
+	// A stack in review was only created to hold a changeset, it has no previous values
+	if len(response.Stacks) != 1 || response.Stacks[0].StackStatus == types.StackStatusReviewInProgress {
+		return []types.Parameter{}, nil
+	}
 
-	return inReview, nil
+	return response.Stacks[0].Parameters, nil
 }
 
 func (c Client) changeSetStatusWatcher(ctx context.Context) error {
@@ -127,7 +265,7 @@ func (c Client) changeSetStatusWatcher(ctx context.Context) error {
 	}
 
 	start := time.Now()
-	var prevStatus string
+	var prevStatus types.ChangeSetStatus
 
 	for {
 		result, err := c.client.DescribeChangeSet(ctx, params)
@@ -135,46 +273,138 @@ func (c Client) changeSetStatusWatcher(ctx context.Context) error {
 			return err
 		}
 
-		status := string(result.Status)
+		terminal := isTerminalChangeSetStatus(result.Status)
+		if terminal || prevStatus != result.Status {
+			c.Emit(Event{
+				Phase:       PhaseChangeset,
+				Type:        EventChangesetStatus,
+				ChangesetID: aws.ToString(c.changesetID),
+				Status:      string(result.Status),
+				Reason:      aws.ToString(result.StatusReason),
+			})
+
+			prevStatus = result.Status
+		}
 
-		if prevStatus == status {
-			time.Sleep(c.pollIntervel)
-			continue
+		if terminal {
+			return c.changeSetResult(ctx, result)
 		}
 
-		if isTerminalStatus(status) {
-			var message string
+		if time.Since(start) > c.timeout {
+			break
+		}
 
-			if result.StatusReason != nil {
-				message = *result.StatusReason
-			}
+		time.Sleep(c.pollIntervel)
+	}
 
-			log.Info().
-				Str("changesetId", *c.changesetID).
-				Str("stackName", c.stackID).
-				Str("status", status).
-				Str("phase", "Changeset").
-				Msg(message)
-			return nil
-		}
+	return fmt.Errorf("changeset failed to reach a terminal state: %w", ErrTimeout)
+}
 
-		log.Info().
-			Str("Phase", "Changeset").
-			Str("ChangesetId", *c.changesetID).
-			Str("StackName", c.stackID).
-			Str("Status", status).
-			Msg("")
+// changeSetResult returns nil for a changeset that can be executed. A changeset that failed for having no
+// changes is a NoChangesError, and is deleted when the client is set to, any other failure is a ChangeSetFailedError.
+func (c Client) changeSetResult(ctx context.Context, result *cloudformation.DescribeChangeSetOutput) error {
+	if result.Status != types.ChangeSetStatusFailed {
+		return nil
+	}
 
-		prevStatus = status
+	reason := aws.ToString(result.StatusReason)
+	if !isNoChangesReason(reason) {
+		return &ChangeSetFailedError{ChangesetID: aws.ToString(c.changesetID), Reason: reason}
+	}
 
-		if time.Since(start) > c.timeout {
-			break
+	log.Info().Str("phase", "Changeset").Msg("No changes in changeset")
+
+	if !c.deleteEmptyChangeset {
+		return &NoChangesError{Reason: reason}
+	}
+
+	_, err := c.client.DeleteChangeSet(ctx, &cloudformation.DeleteChangeSetInput{
+		ChangeSetName: c.changesetID,
+		StackName:     aws.String(c.stackID),
+	})
+	if err != nil {
+		return fmt.Errorf("unable to delete the empty changeset: %w", err)
+	}
+
+	log.Info().Str("phase", "Changeset").Str("changesetId", aws.ToString(c.changesetID)).Msg("Deleted empty changeset")
+
+	return &NoChangesError{Reason: reason}
+}
+
+// Changes returns every change in the current changeset, following pagination
+func (c Client) Changes(ctx context.Context) ([]types.Change, error) {
+	params := &cloudformation.DescribeChangeSetInput{
+		ChangeSetName: c.changesetID,
+		StackName:     aws.String(c.stackID),
+	}
+
+	changes := []types.Change{}
+
+	for {
+		result, err := c.client.DescribeChangeSet(ctx, params)
+		if err != nil {
+			return nil, err
 		}
 
-		time.Sleep(c.pollIntervel)
+		changes = append(changes, result.Changes...)
+
+		if result.NextToken == nil {
+			return changes, nil
+		}
+
+		params.NextToken = result.NextToken
+	}
+}
+
+// ValidateTemplate asks CloudFormation to validate the template and returns the capabilities
+// and parameters it declares
+func (c Client) ValidateTemplate(ctx context.Context, template []byte) (*cloudformation.ValidateTemplateOutput, error) {
+	return c.client.ValidateTemplate(ctx, &cloudformation.ValidateTemplateInput{
+		TemplateBody: aws.String(string(template)),
+	})
+}
+
+// SetStackPolicy replaces the policy that protects the stack's resources from updates
+func (c Client) SetStackPolicy(ctx context.Context, policy []byte) error {
+	_, err := c.client.SetStackPolicy(ctx, &cloudformation.SetStackPolicyInput{
+		StackName:       aws.String(c.stackID),
+		StackPolicyBody: aws.String(string(policy)),
+	})
+	if err != nil {
+		return fmt.Errorf("unable to set the stack policy: %w", err)
+	}
+
+	log.Info().Msg("Stack policy set")
+
+	return nil
+}
+
+// Outputs returns the outputs of the stack
+func (c Client) Outputs(ctx context.Context) ([]types.Output, error) {
+	stack, err := c.Stack(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return stack.Outputs, nil
+}
+
+// Stack describes the stack
+func (c Client) Stack(ctx context.Context) (*types.Stack, error) {
+	params := &cloudformation.DescribeStacksInput{
+		StackName: aws.String(c.stackID),
+	}
+
+	result, err := c.client.DescribeStacks(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(result.Stacks) != 1 {
+		return nil, fmt.Errorf("expected 1 stack named %s but found %d", c.stackID, len(result.Stacks))
 	}
 
-	return errors.New("changeset failed to reach a terminal state")
+	return &result.Stacks[0], nil
 }
 
 func (c Client) ExecuteChangeSet(ctx context.Context) error {
@@ -191,14 +421,18 @@ func (c Client) ExecuteChangeSet(ctx context.Context) error {
 
 	if len(result.Changes) == 0 {
 		log.Info().Str("phase", "Execution").Msg("No changes in changeset")
-		return nil
+		return &NoChangesError{}
 	}
 
 	log.Info().Str("phase", "Execution").Msg("Executing changeset")
 
+	token := newOperationToken()
+	tracker := newEventTracker(c.stackID, token, time.Now())
+
 	input := &cloudformation.ExecuteChangeSetInput{
-		StackName:     aws.String(c.stackID),
-		ChangeSetName: c.changesetID,
+		StackName:          aws.String(c.stackID),
+		ChangeSetName:      c.changesetID,
+		ClientRequestToken: aws.String(token),
 	}
 
 	_, err = c.client.ExecuteChangeSet(ctx, input)
@@ -206,7 +440,7 @@ func (c Client) ExecuteChangeSet(ctx context.Context) error {
 		return err
 	}
 
-	return c.runWatchers(ctx)
+	return c.runWatchers(ctx, tracker)
 }
 
 func (c Client) ExecuteDestroyStack(ctx context.Context) error {
@@ -219,27 +453,24 @@ func (c Client) ExecuteDestroyStack(ctx context.Context) error {
 		return nil
 	}
 
-	log.Debug().Str("phase", "Execution").Msg("Priming cache")
-
-	err := c.primeEventCache(ctx)
-	if err != nil {
-		return err
-	}
-
 	log.Info().Str("phase", "Execution").Msg("Destroying stack")
 
+	token := newOperationToken()
+	tracker := newEventTracker(c.stackID, token, time.Now())
+
 	input := &cloudformation.DeleteStackInput{
-		StackName: aws.String(c.stackID),
+		StackName:          aws.String(c.stackID),
+		ClientRequestToken: aws.String(token),
 	}
 
-	_, err = c.client.DeleteStack(ctx, input)
+	_, err := c.client.DeleteStack(ctx, input)
 	if err != nil {
 		return err
 	}
 
-	if err = c.runWatchers(ctx); err != nil {
+	if err = c.runWatchers(ctx, tracker); err != nil {
 		// On destroy we will hit this error so we know the stack is gone, anything else should return
-		if !errorIsDoesNotExist(err) {
+		if !isNotFound(err) {
 			return err
 		}
 		log.Info().Str("phase", "Execution").Msg("Stack destroyed successfully")
@@ -248,30 +479,85 @@ func (c Client) ExecuteDestroyStack(ctx context.Context) error {
 	return nil
 }
 
-func (c Client) runWatchers(ctx context.Context) error {
-	ctx, cancel := context.WithTimeoutCause(ctx, c.timeout, errors.New("reached timout"))
+// ContinueUpdateRollback continues rolling back a stack stuck in UPDATE_ROLLBACK_FAILED, skipping the resources
+// that can't be rolled back, and waits for it to reach UPDATE_ROLLBACK_COMPLETE
+func (c Client) ContinueUpdateRollback(ctx context.Context, resourcesToSkip []string) error {
+	log.Info().Str("phase", "Execution").Strs("skip", resourcesToSkip).Msg("Continuing update rollback")
 
-	var errGroup errgroup.Group
+	token := newOperationToken()
+	tracker := newEventTracker(c.stackID, token, time.Now())
 
-	errGroup.Go(func() error { return c.stackStatusWatcher(ctx, cancel) })
-	errGroup.Go(func() error { return c.changeSetExecutionStatusWatcher(ctx, cancel) })
+	input := &cloudformation.ContinueUpdateRollbackInput{
+		StackName:          aws.String(c.stackID),
+		ResourcesToSkip:    resourcesToSkip,
+		ClientRequestToken: aws.String(token),
+	}
 
-	if err := errGroup.Wait(); err != nil {
+	if _, err := c.client.ContinueUpdateRollback(ctx, input); err != nil {
 		return err
 	}
 
-	if ctx.Err() != nil {
+	// Rolled back is the status the rollback is meant to finish in, so it isn't an error here
+	err := c.runWatchers(ctx, tracker)
+
+	var statusErr *StackStatusError
+	if errors.As(err, &statusErr) && statusErr.Status == types.StackStatusUpdateRollbackComplete {
+		return nil
+	}
+
+	return err
+}
+
+// runWatchers follows the stack until it reaches a terminal status and returns an error unless
+// that status is a successful one
+func (c Client) runWatchers(parentCtx context.Context, tracker *eventTracker) error {
+	ctx, cancel := context.WithTimeoutCause(parentCtx, c.timeout, ErrTimeout)
+	defer cancel()
+
+	var errGroup errgroup.Group
+	var stack *types.Stack
+
+	errGroup.Go(func() error {
+		var err error
+		stack, err = c.stackStatusWatcher(ctx, cancel)
+		return err
+	})
+	errGroup.Go(func() error { return c.changeSetExecutionStatusWatcher(ctx, cancel, tracker) })
+
+	err := errGroup.Wait()
+
+	// Once the stack finished its status is the result, the event watcher may have been canceled mid poll
+	if stack != nil {
+		return c.stackOutcome(parentCtx, stack, tracker)
+	}
+
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
 		// We don't care if the context was canceled since that's how we signal the go routines
-		// so only log if the deadline was exceeded
-		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
-			log.Info().Msg("Reached timeout deadline waiting for stack to complete")
-		}
+		// so only report if the deadline was exceeded
+		log.Info().Msg("Reached timeout deadline waiting for stack to complete")
+		return ErrTimeout
 	}
 
-	return nil
+	if err != nil {
+		return err
+	}
+
+	return ErrTimeout
+}
+
+// stackOutcome returns the error for the status the stack finished in, with the root causes of a failure
+func (c Client) stackOutcome(ctx context.Context, stack *types.Stack, tracker *eventTracker) error {
+	err := stackResult(stack)
+
+	var statusErr *StackStatusError
+	if errors.As(err, &statusErr) {
+		c.summarizeFailure(ctx, statusErr, tracker)
+	}
+
+	return err
 }
 
-func (c Client) stackStatusWatcher(ctx context.Context, cancel context.CancelFunc) error {
+func (c Client) stackStatusWatcher(ctx context.Context, cancel context.CancelFunc) (*types.Stack, error) {
 	defer cancel()
 
 	params := &cloudformation.DescribeStacksInput{
@@ -281,21 +567,27 @@ func (c Client) stackStatusWatcher(ctx context.Context, cancel context.CancelFun
 	for {
 		result, err := c.client.DescribeStacks(ctx, params)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
-		stack := result.Stacks[0]
+		if len(result.Stacks) == 0 {
+			return nil, &NotFoundError{Err: fmt.Errorf("stack %s does not exist", c.stackID)}
+		}
 
-		if isTerminalStatus(string(stack.StackStatus)) {
-			log.Info().
-				Str("phase", "Execution").
-				Str("event_type", "Deployment").
-				Str("provisioner_resource_id", c.stackID).
-				Str("provider_resource_id", "").
-				Str("status", string(stack.StackStatus)).
-				Msg("")
+		stack := result.Stacks[0]
 
-			return nil
+		if IsTerminalStackStatus(stack.StackStatus) {
+			c.Emit(Event{
+				Phase:      PhaseExecution,
+				Type:       EventStackStatus,
+				StackPath:  c.stackID,
+				PhysicalID: aws.ToString(stack.StackId),
+				Status:     string(stack.StackStatus),
+				Category:   CategorizeStackStatus(stack.StackStatus).String(),
+				Reason:     aws.ToString(stack.StackStatusReason),
+			})
+
+			return &stack, nil
 		}
 
 		// Sleep before the context check so if it was canceled we exit without trying the API again
@@ -304,40 +596,23 @@ func (c Client) stackStatusWatcher(ctx context.Context, cancel context.CancelFun
 		select {
 		case <-ctx.Done():
 			log.Debug().Str("phase", "Execution").Str("func", "stack").Msg("ctx canceled")
-			return nil
+			return nil, context.Cause(ctx)
 		default:
 			log.Debug().Str("phase", "Execution").Msg("stack Going to poll again")
 		}
 	}
 }
 
-func (c *Client) changeSetExecutionStatusWatcher(ctx context.Context, cancel context.CancelFunc) error {
+func (c Client) changeSetExecutionStatusWatcher(ctx context.Context, cancel context.CancelFunc, tracker *eventTracker) error {
 	defer cancel()
 
-	params := &cloudformation.DescribeStackEventsInput{
-		StackName: aws.String(c.stackID),
-	}
-
 	for {
-		result, err := c.client.DescribeStackEvents(ctx, params)
-		if err != nil {
-			return err
-		}
-
-		for i := len(result.StackEvents) - 1; i >= 0; i-- {
-			event := result.StackEvents[i]
-
-			if !c.eventCache.EventExists(*event.EventId) {
-				e := c.eventCache.EventFromStack(event, "Resource")
-				c.eventCache.AddEvent(*event.EventId, e)
-				log.Info().
-					Str("phase", "Execution").
-					Str("event_type", e.Type).
-					Str("provisioner_resource_id", e.ResourceName).
-					Str("provider_resource_id", e.ProviderResourceID).
-					Str("status", e.ResourceStatus).
-					Msg("")
+		if err := c.pollStackEvents(ctx, tracker); err != nil {
+			if ctx.Err() != nil {
+				// Canceled mid poll because the stack finished or timed out, runWatchers reports which
+				return nil
 			}
+			return err
 		}
 
 		// Sleep before the context check so if it was canceled we exit without trying the API again
@@ -354,23 +629,37 @@ func (c *Client) changeSetExecutionStatusWatcher(ctx context.Context, cancel con
 	}
 }
 
-func isTerminalStatus(status string) bool {
-	switch status {
-	case string(types.ChangeSetStatusFailed):
-		return true
-	case string(types.ChangeSetStatusCreateComplete):
-		return true
-	case string(types.StackStatusUpdateComplete):
-		return true
-	case string(types.StackStatusUpdateFailed):
-		return true
-	case string(types.StackStatusCreateFailed):
-		return true
-	default:
-		return false
+// pollStackEvents emits every new event of the tracked operation
+func (c Client) pollStackEvents(ctx context.Context, tracker *eventTracker) error {
+	events, err := tracker.poll(ctx, c.client)
+	if err != nil {
+		return err
 	}
+
+	for _, e := range events {
+		c.Emit(Event{
+			Timestamp:    e.Timestamp,
+			Phase:        PhaseExecution,
+			Type:         EventResource,
+			StackPath:    e.StackPath,
+			LogicalID:    e.ResourceName,
+			PhysicalID:   e.ProviderResourceID,
+			ResourceType: e.ResourceType,
+			Status:       e.ResourceStatus,
+			Reason:       e.Message,
+		})
+	}
+
+	return nil
 }
 
-func errorIsDoesNotExist(err error) bool {
-	return strings.Contains(err.Error(), "does not exist")
+// summarizeFailure attaches the root causes of a failed operation to its error and emits them
+func (c Client) summarizeFailure(ctx context.Context, statusErr *StackStatusError, tracker *eventTracker) {
+	// The event watcher may have stopped before the last events arrived, so poll once more
+	if err := c.pollStackEvents(ctx, tracker); err != nil {
+		log.Debug().Err(err).Str("phase", "Summary").Msg("unable to poll final stack events")
+	}
+
+	statusErr.Failures = RootCauses(tracker.Events())
+	c.emitFailures(statusErr.Failures)
 }
@@ -2,7 +2,9 @@ package client_test
 
 import (
 	"context"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
@@ -10,6 +12,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
 	"github.com/aws/smithy-go/middleware"
 	"github.com/massdriver-cloud/fogmachine/pkg/client"
+	"github.com/massdriver-cloud/fogmachine/pkg/testing/fake"
 	"github.com/massdriver-cloud/fogmachine/pkg/testing/mock"
 )
 
@@ -37,7 +40,7 @@ func TestCreate(t *testing.T) {
 		t.FailNow()
 	}
 
-	err = cf.CreateChangeset(context.Background(), nil, nil)
+	err = cf.CreateChangeset(context.Background(), nil, nil, client.StackOptions{})
 	if err != nil {
 		t.Error(err)
 		t.Fail()
@@ -49,3 +52,141 @@ func TestCreate(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestAdoptChangeset(t *testing.T) {
+	tests := map[string]struct {
+		output  cloudformation.DescribeChangeSetOutput
+		wantErr bool
+	}{
+		"available": {
+			output: cloudformation.DescribeChangeSetOutput{
+				ChangeSetId:     aws.String("arn:foo"),
+				StackName:       aws.String("bar"),
+				Status:          types.ChangeSetStatusCreateComplete,
+				ExecutionStatus: types.ExecutionStatusAvailable,
+			},
+		},
+		"obsolete": {
+			output: cloudformation.DescribeChangeSetOutput{
+				ChangeSetId:     aws.String("arn:foo"),
+				StackName:       aws.String("bar"),
+				Status:          types.ChangeSetStatusCreateComplete,
+				ExecutionStatus: types.ExecutionStatusObsolete,
+			},
+			wantErr: true,
+		},
+		"failed": {
+			output: cloudformation.DescribeChangeSetOutput{
+				ChangeSetId: aws.String("arn:foo"),
+				StackName:   aws.String("bar"),
+				Status:      types.ChangeSetStatusFailed,
+			},
+			wantErr: true,
+		},
+		"other stack": {
+			output: cloudformation.DescribeChangeSetOutput{
+				ChangeSetId:     aws.String("arn:foo"),
+				StackName:       aws.String("baz"),
+				Status:          types.ChangeSetStatusCreateComplete,
+				ExecutionStatus: types.ExecutionStatusAvailable,
+			},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			cfMock := mock.NewCloudFormationMock()
+			cfMock.SetDescribeChangeSetReturn(tc.output)
+
+			cfg, err := config.LoadDefaultConfig(context.Background(), config.WithRegion("us-west-2"), config.WithAPIOptions([]func(*middleware.Stack) error{cfMock.CloudFormationMiddlewareInjector()}))
+			if err != nil {
+				t.FailNow()
+			}
+
+			cf, err := client.NewCloudformationClientWithCFClient("bar", 5, 5, cloudformation.NewFromConfig(cfg))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = cf.AdoptChangeset(context.Background(), "foo")
+			if tc.wantErr != (err != nil) {
+				t.Fatalf("Got error %v but expected error %t", err, tc.wantErr)
+			}
+
+			if !tc.wantErr && cf.ChangesetID() != "arn:foo" {
+				t.Fatalf("Got changeset %s but expected arn:foo", cf.ChangesetID())
+			}
+		})
+	}
+}
+
+// noStacks is a CloudFormation that describes every stack as an empty list
+type noStacks struct {
+	*fake.CloudFormation
+}
+
+func (noStacks) DescribeStacks(context.Context, *cloudformation.DescribeStacksInput, ...func(*cloudformation.Options)) (*cloudformation.DescribeStacksOutput, error) {
+	return &cloudformation.DescribeStacksOutput{}, nil
+}
+
+func TestExecuteChangeSetWithoutStacks(t *testing.T) {
+	ctx := context.Background()
+
+	cf, err := client.New(ctx, client.Config{StackName: "bar", PollInterval: time.Millisecond, CloudFormation: noStacks{fake.New()}, Events: client.FuncSink(func(client.Event) {})})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = cf.CreateChangeset(ctx, []byte(bucketTemplate), nil, client.StackOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	err = cf.ExecuteChangeSet(ctx)
+
+	var notFound *client.NotFoundError
+	if !errors.As(err, &notFound) {
+		t.Fatalf("Got %v but expected a NotFoundError", err)
+	}
+}
+
+// blockedEvents is a CloudFormation whose DescribeStackEvents doesn't return until the caller gives up once a
+// changeset is executing
+type blockedEvents struct {
+	*fake.CloudFormation
+	executing chan struct{}
+}
+
+func (b blockedEvents) ExecuteChangeSet(ctx context.Context, params *cloudformation.ExecuteChangeSetInput, optFns ...func(*cloudformation.Options)) (*cloudformation.ExecuteChangeSetOutput, error) {
+	close(b.executing)
+	return b.CloudFormation.ExecuteChangeSet(ctx, params, optFns...)
+}
+
+func (b blockedEvents) DescribeStackEvents(ctx context.Context, params *cloudformation.DescribeStackEventsInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DescribeStackEventsOutput, error) {
+	select {
+	case <-b.executing:
+		<-ctx.Done()
+		return nil, ctx.Err()
+	default:
+		return b.CloudFormation.DescribeStackEvents(ctx, params, optFns...)
+	}
+}
+
+func TestExecuteChangeSetCanceledEventPoll(t *testing.T) {
+	ctx := context.Background()
+	api := blockedEvents{CloudFormation: fake.New(), executing: make(chan struct{})}
+
+	cf, err := client.New(ctx, client.Config{StackName: "bar", Timeout: 5 * time.Second, PollInterval: time.Millisecond, CloudFormation: api, Events: client.FuncSink(func(client.Event) {})})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = cf.CreateChangeset(ctx, []byte(bucketTemplate), nil, client.StackOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The stack finishes while the event watcher is still waiting on DescribeStackEvents
+	if err = cf.ExecuteChangeSet(ctx); err != nil {
+		t.Fatalf("Got %v but expected the successful stack status to be the result", err)
+	}
+}
@@ -0,0 +1,254 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/aws/smithy-go"
+)
+
+var (
+	ErrNoChanges = errors.New("changeset contains no changes")
+	ErrTimeout   = errors.New("timed out waiting for CloudFormation")
+	// ErrSinkClosed is returned by a sink that was sent an event after it was closed
+	ErrSinkClosed = errors.New("event sink is closed")
+)
+
+// The client returns CloudFormation API errors as one of the typed errors below, or as is when they don't fit.
+// Every typed error wraps the API error and has a hint for the person running fogmachine.
+
+// NotFoundError is returned when the stack or changeset doesn't exist
+type NotFoundError struct {
+	Err error
+}
+
+func (e *NotFoundError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *NotFoundError) Unwrap() error {
+	return e.Err
+}
+
+// Hint suggests how to fix the error
+func (e *NotFoundError) Hint() string {
+	return "check the package name and region, the stack may not have been created yet or may have been deleted"
+}
+
+// ValidationError is returned when CloudFormation rejects the template, parameters or request
+type ValidationError struct {
+	Err error
+}
+
+func (e *ValidationError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// Hint suggests how to fix the error
+func (e *ValidationError) Hint() string {
+	return "fix the template or parameters, fogmachine validate checks them before a deploy"
+}
+
+// ThrottlingError is returned when CloudFormation throttled the request even after the SDK's retries
+type ThrottlingError struct {
+	Err error
+}
+
+func (e *ThrottlingError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ThrottlingError) Unwrap() error {
+	return e.Err
+}
+
+// Hint suggests how to fix the error
+func (e *ThrottlingError) Hint() string {
+	return "too many CloudFormation calls in this account and region, retry later or raise --poll-interval"
+}
+
+// InsufficientCapabilitiesError is returned when the template needs capabilities that weren't acknowledged
+type InsufficientCapabilitiesError struct {
+	Capabilities []types.Capability
+	Err          error
+}
+
+func (e *InsufficientCapabilitiesError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *InsufficientCapabilitiesError) Unwrap() error {
+	return e.Err
+}
+
+// Hint suggests how to fix the error
+func (e *InsufficientCapabilitiesError) Hint() string {
+	if len(e.Capabilities) == 0 {
+		return "acknowledge the capabilities the template needs with --capabilities"
+	}
+
+	names := make([]string, 0, len(e.Capabilities))
+	for _, capability := range e.Capabilities {
+		names = append(names, string(capability))
+	}
+	return "acknowledge the capabilities with --capabilities " + strings.Join(names, ",")
+}
+
+// NotUpdatableError is returned when the stack is in a status that doesn't allow changes
+type NotUpdatableError struct {
+	Status types.StackStatus
+	Err    error
+}
+
+func (e *NotUpdatableError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("stack is in %s state and can not be updated", e.Status)
+	}
+	return e.Err.Error()
+}
+
+func (e *NotUpdatableError) Unwrap() error {
+	return e.Err
+}
+
+// Hint suggests how to fix the error
+func (e *NotUpdatableError) Hint() string {
+	switch {
+	case e.Status == types.StackStatusRollbackComplete || e.Status == types.StackStatusRollbackFailed || e.Status == types.StackStatusCreateFailed:
+		return "the stack was never created successfully and can only be deleted, apply with --recreate-failed to delete and create it again"
+	case e.Status == types.StackStatusUpdateRollbackFailed:
+		return "apply with --continue-rollback to finish the rollback first, adding --skip-resource for resources that can't roll back"
+	case strings.HasSuffix(string(e.Status), "_IN_PROGRESS"):
+		return "another operation is running on the stack, wait for it to finish or use --on-busy wait"
+	default:
+		return "the stack must be in a *_COMPLETE status to be changed"
+	}
+}
+
+// NoChangesError is returned when the changeset wouldn't change anything. It matches ErrNoChanges with errors.Is.
+type NoChangesError struct {
+	Reason string
+}
+
+func (e *NoChangesError) Error() string {
+	if e.Reason == "" {
+		return ErrNoChanges.Error()
+	}
+	return fmt.Sprintf("%s: %s", ErrNoChanges, e.Reason)
+}
+
+// Is makes errors.Is(err, ErrNoChanges) true
+func (e *NoChangesError) Is(target error) bool {
+	return target == ErrNoChanges
+}
+
+// Hint suggests how to fix the error
+func (e *NoChangesError) Hint() string {
+	return "the template and parameters already match the stack"
+}
+
+// ChangeSetFailedError is returned when CloudFormation couldn't create the changeset for a reason other than it
+// having no changes
+type ChangeSetFailedError struct {
+	ChangesetID string
+	Reason      string
+}
+
+func (e *ChangeSetFailedError) Error() string {
+	return fmt.Sprintf("changeset %s failed: %s", e.ChangesetID, e.Reason)
+}
+
+// Hint suggests how to fix the error
+func (e *ChangeSetFailedError) Hint() string {
+	return "the reason names the template, parameter or resource CloudFormation couldn't plan, fix it and plan again"
+}
+
+// AccessDeniedError is returned when the credentials aren't allowed to make the call
+type AccessDeniedError struct {
+	Err error
+}
+
+func (e *AccessDeniedError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *AccessDeniedError) Unwrap() error {
+	return e.Err
+}
+
+// Hint suggests how to fix the error
+func (e *AccessDeniedError) Hint() string {
+	return "the AWS credentials, or the --role-arn CloudFormation assumes, are missing IAM permissions for the call"
+}
+
+// Hint returns the hint of the first typed error in err's chain, empty when there isn't one
+func Hint(err error) string {
+	var hinted interface{ Hint() string }
+	if errors.As(err, &hinted) {
+		return hinted.Hint()
+	}
+	return ""
+}
+
+var (
+	capabilitiesPattern = regexp.MustCompile(`CAPABILITY_[A-Z_]+`)
+	statusPattern       = regexp.MustCompile(`is in ([A-Z_]+) state`)
+	// notFoundPattern matches the messages of a missing stack or changeset, other things that "do not exist",
+	// such as a role or an S3 template, are validation errors
+	notFoundPattern = regexp.MustCompile(`^(Stack with id .+|Stack \[.+\]|ChangeSet .+) does not exist`)
+)
+
+// classify turns a CloudFormation API error into one of the typed errors, other errors are returned as is
+func classify(err error) error {
+	var apiErr smithy.APIError
+	if err == nil || !errors.As(err, &apiErr) {
+		return err
+	}
+
+	code, message := apiErr.ErrorCode(), apiErr.ErrorMessage()
+
+	switch {
+	case code == "InsufficientCapabilitiesException":
+		capabilities := []types.Capability{}
+		for _, match := range capabilitiesPattern.FindAllString(message, -1) {
+			capabilities = append(capabilities, types.Capability(match))
+		}
+		return &InsufficientCapabilitiesError{Capabilities: capabilities, Err: err}
+	case code == "Throttling" || code == "ThrottlingException" || code == "RequestLimitExceeded" || code == "TooManyRequestsException":
+		return &ThrottlingError{Err: err}
+	case strings.Contains(code, "AccessDenied") || code == "UnauthorizedOperation":
+		return &AccessDeniedError{Err: err}
+	case code == "ChangeSetNotFound" || code == "StackNotFoundException" || notFoundPattern.MatchString(message):
+		return &NotFoundError{Err: err}
+	case strings.Contains(message, "No updates are to be performed"):
+		return &NoChangesError{Reason: message}
+	case strings.Contains(message, "can not be updated") || strings.Contains(message, "cannot be updated"):
+		notUpdatable := &NotUpdatableError{Err: err}
+		if match := statusPattern.FindStringSubmatch(message); match != nil {
+			notUpdatable.Status = types.StackStatus(match[1])
+		}
+		return notUpdatable
+	case code == "ValidationError" || code == "InvalidChangeSetStatus" || code == "AlreadyExistsException":
+		return &ValidationError{Err: err}
+	}
+
+	return err
+}
+
+// isNoChangesReason reports whether a FAILED changeset's status reason says it failed for having no changes
+func isNoChangesReason(reason string) bool {
+	return strings.Contains(reason, "didn't contain changes") || strings.Contains(reason, "No updates are to be performed")
+}
+
+// isNotFound reports whether the error says the stack or changeset doesn't exist
+func isNotFound(err error) bool {
+	var notFound *NotFoundError
+	return errors.As(classify(err), &notFound)
+}
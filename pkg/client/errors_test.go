@@ -0,0 +1,131 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/aws/smithy-go"
+	"github.com/massdriver-cloud/fogmachine/pkg/client"
+	"github.com/massdriver-cloud/fogmachine/pkg/testing/fake"
+)
+
+const bucketTemplate = `Resources:
+  Bucket:
+    Type: AWS::S3::Bucket
+`
+
+func TestErrorClassification(t *testing.T) {
+	ctx := context.Background()
+
+	cf := fake.New()
+	if err := cf.AddStack("failed", types.StackStatusRollbackComplete, bucketTemplate); err != nil {
+		t.Fatal(err)
+	}
+
+	newClient := func(stackName string) *client.Client {
+		c, err := client.New(ctx, client.Config{StackName: stackName, CloudFormation: cf, Events: client.FuncSink(func(client.Event) {})})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	t.Run("insufficient capabilities", func(t *testing.T) {
+		role := "Resources:\n  Role:\n    Type: AWS::IAM::Role\n"
+		err := newClient("iam").CreateChangeset(ctx, []byte(role), nil, client.StackOptions{})
+
+		var capabilitiesErr *client.InsufficientCapabilitiesError
+		if !errors.As(err, &capabilitiesErr) {
+			t.Fatalf("Got %v but expected an InsufficientCapabilitiesError", err)
+		}
+		if len(capabilitiesErr.Capabilities) != 1 || capabilitiesErr.Capabilities[0] != types.CapabilityCapabilityIam {
+			t.Fatalf("Got %v but expected [CAPABILITY_IAM]", capabilitiesErr.Capabilities)
+		}
+		if hint := client.Hint(err); !strings.Contains(hint, "--capabilities CAPABILITY_IAM") {
+			t.Fatalf("Got %s but expected a hint naming the capability", hint)
+		}
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		err := newClient("invalid").CreateChangeset(ctx, []byte("Resources: {}"), nil, client.StackOptions{})
+
+		var validationErr *client.ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Fatalf("Got %v but expected a ValidationError", err)
+		}
+	})
+
+	t.Run("not updatable", func(t *testing.T) {
+		err := newClient("failed").CreateChangeset(ctx, []byte(bucketTemplate), nil, client.StackOptions{})
+
+		var notUpdatable *client.NotUpdatableError
+		if !errors.As(err, &notUpdatable) {
+			t.Fatalf("Got %v but expected a NotUpdatableError", err)
+		}
+		if notUpdatable.Status != types.StackStatusRollbackComplete {
+			t.Fatalf("Got %s but expected %s", notUpdatable.Status, types.StackStatusRollbackComplete)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		err := newClient("failed").AdoptChangeset(ctx, "missing")
+
+		var notFound *client.NotFoundError
+		if !errors.As(err, &notFound) {
+			t.Fatalf("Got %v but expected a NotFoundError", err)
+		}
+	})
+}
+
+// apiErrors fails CreateChangeSet with the error
+type apiErrors struct {
+	*fake.CloudFormation
+	err error
+}
+
+func (a apiErrors) CreateChangeSet(context.Context, *cloudformation.CreateChangeSetInput, ...func(*cloudformation.Options)) (*cloudformation.CreateChangeSetOutput, error) {
+	return nil, a.err
+}
+
+func TestNotFoundClassification(t *testing.T) {
+	tests := map[string]struct {
+		message  string
+		notFound bool
+	}{
+		"stack id":         {message: "Stack with id bar does not exist", notFound: true},
+		"stack name":       {message: "Stack [bar] does not exist", notFound: true},
+		"changeset":        {message: "ChangeSet [fogmachine-1] does not exist", notFound: true},
+		"role":             {message: "Role arn:aws:iam::123456789012:role/cfn-deploy does not exist"},
+		"template url":     {message: "S3 error: The specified key does not exist."},
+		"ssm parameter":    {message: "Parameters: [ssm:/app/db-password] cannot be found. Parameter /app/db-password does not exist"},
+		"export":           {message: "No export named vpc-id found. Export vpc-id does not exist"},
+		"nested stack url": {message: "TemplateURL must be a supported URL, https://bucket.s3.amazonaws.com/network.yaml does not exist"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			api := apiErrors{CloudFormation: fake.New(), err: &smithy.GenericAPIError{Code: "ValidationError", Message: tc.message}}
+
+			c, err := client.New(context.Background(), client.Config{StackName: "bar", CloudFormation: api, Events: client.FuncSink(func(client.Event) {})})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = c.CreateChangeset(context.Background(), []byte(bucketTemplate), nil, client.StackOptions{})
+
+			var notFound *client.NotFoundError
+			if errors.As(err, &notFound) != tc.notFound {
+				t.Fatalf("Got %v but expected NotFoundError %t", err, tc.notFound)
+			}
+
+			var validationErr *client.ValidationError
+			if !tc.notFound && !errors.As(err, &validationErr) {
+				t.Fatalf("Got %v but expected a ValidationError", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package client
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/massdriver-cloud/fogmachine/pkg/redact"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// SchemaVersion is the version of the Event schema. It changes when a field is removed or its meaning changes,
+// new fields may be added without a new version.
+const SchemaVersion = "1"
+
+// Event phases
+const (
+	PhaseChangeset = "Changeset"
+	PhaseExecution = "Execution"
+	PhaseSummary   = "Summary"
+)
+
+// Event types
+const (
+	// EventChangesetStatus is a change in the status of the changeset while it's created
+	EventChangesetStatus = "ChangesetStatus"
+	// EventStackStatus is the terminal status of the stack once an operation finishes
+	EventStackStatus = "StackStatus"
+	// EventResource is a stack event for a resource, or a stack, during the operation
+	EventResource = "Resource"
+	// EventFailure is a root cause of a failed operation
+	EventFailure = "Failure"
+	// EventResourceChange is a change a plan would make to a resource
+	EventResourceChange = "ResourceChange"
+	// EventParameterChange is a change a plan would make to a parameter
+	EventParameterChange = "ParameterChange"
+)
+
+// Event is a single entry of the event stream. Fields that don't apply to the event type are omitted.
+type Event struct {
+	SchemaVersion string    `json:"schemaVersion"`
+	Timestamp     time.Time `json:"timestamp"`
+	OperationID   string    `json:"operationId"`
+	Phase         string    `json:"phase"`
+	Type          string    `json:"type"`
+	StackName     string    `json:"stackName"`
+	StackPath     string    `json:"stackPath,omitempty"`
+	ChangesetID   string    `json:"changesetId,omitempty"`
+	LogicalID     string    `json:"logicalId,omitempty"`
+	PhysicalID    string    `json:"physicalId,omitempty"`
+	ResourceType  string    `json:"resourceType,omitempty"`
+	Status        string    `json:"status,omitempty"`
+	Category      string    `json:"category,omitempty"`
+	Reason        string    `json:"reason,omitempty"`
+	Action        string    `json:"action,omitempty"`
+	Replacement   string    `json:"replacement,omitempty"`
+	Before        string    `json:"before,omitempty"`
+	After         string    `json:"after,omitempty"`
+}
+
+// Message is a short human readable summary of the event
+func (e Event) Message() string {
+	subject := e.LogicalID
+	if subject == "" {
+		subject = e.StackName
+	}
+
+	switch e.Type {
+	case EventChangesetStatus:
+		subject = "Changeset"
+	case EventResourceChange, EventParameterChange:
+		return fmt.Sprintf("%s %s", subject, e.Action)
+	case EventFailure:
+		return fmt.Sprintf("%s/%s: %s", e.StackPath, e.LogicalID, e.Reason)
+	}
+
+	if e.Reason != "" {
+		return fmt.Sprintf("%s %s: %s", subject, e.Status, e.Reason)
+	}
+
+	return fmt.Sprintf("%s %s", subject, e.Status)
+}
+
+// log writes the event to the diagnostic log using the same field names as the JSON schema
+func (e Event) log() {
+	level := zerolog.InfoLevel
+	if e.Type == EventFailure {
+		level = zerolog.ErrorLevel
+	}
+
+	entry := log.WithLevel(level).Str("phase", e.Phase).Str("type", e.Type)
+	for _, field := range [][2]string{
+		{"stackPath", e.StackPath},
+		{"changesetId", e.ChangesetID},
+		{"logicalId", e.LogicalID},
+		{"physicalId", e.PhysicalID},
+		{"resourceType", e.ResourceType},
+		{"status", e.Status},
+		{"category", e.Category},
+		{"action", e.Action},
+		{"replacement", e.Replacement},
+	} {
+		if field[1] != "" {
+			entry = entry.Str(field[0], field[1])
+		}
+	}
+
+	entry.Msg(e.Message())
+}
+
+// SetEventSink replaces where events are sent, clients start with a ConsoleSink. Events are redacted with the
+// client's redactor before they reach the sink.
+func (c *Client) SetEventSink(sink EventSink) {
+	if sink == nil {
+		sink = ConsoleSink{}
+	}
+	c.sink = redactingSink{sink: sink, redactor: c.redactor}
+}
+
+// Redactor is what masks secrets in the client's events, register the values of NoEcho parameters with it
+func (c Client) Redactor() *redact.Redactor {
+	return c.redactor
+}
+
+// CloseEvents flushes and closes the event sink
+func (c Client) CloseEvents() error {
+	return c.sink.Close()
+}
+
+// OperationID identifies the client's run, it's on every event the client emits. Each stack operation the client
+// starts has a ClientRequestToken of its own so their events are kept apart.
+func (c Client) OperationID() string {
+	return c.operationID
+}
+
+// Emit fills in the schema version, stack, operation and time of the event then sends it to the sink
+func (c Client) Emit(e Event) {
+	e.SchemaVersion = SchemaVersion
+	e.StackName = c.stackID
+	e.OperationID = c.operationID
+	if e.Timestamp.IsZero() {
+		e.Timestamp = time.Now().UTC()
+	}
+
+	if err := c.sink.Send(e); err != nil {
+		log.Warn().Err(err).Str("phase", e.Phase).Msg("unable to send event")
+	}
+}
@@ -0,0 +1,76 @@
+package client_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/massdriver-cloud/fogmachine/pkg/client"
+)
+
+func TestEmit(t *testing.T) {
+	cf, err := client.NewCloudformationClientWithCFClient("bar", 5, 5, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	cf.SetEventSink(client.NewNDJSONSink(&buf))
+
+	timestamp := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	cf.Emit(client.Event{
+		Timestamp:    timestamp,
+		Phase:        client.PhaseExecution,
+		Type:         client.EventResource,
+		StackPath:    "bar",
+		LogicalID:    "MainBucket",
+		PhysicalID:   "bar-mainbucket",
+		ResourceType: "AWS::S3::Bucket",
+		Status:       "CREATE_FAILED",
+		Reason:       "Bucket already exists",
+	})
+	cf.Emit(client.Event{Phase: client.PhaseExecution, Type: client.EventStackStatus, Status: "CREATE_COMPLETE"})
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Got %d lines but expected 2", len(lines))
+	}
+
+	got := map[string]interface{}{}
+	if err = json.Unmarshal([]byte(lines[0]), &got); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]interface{}{
+		"schemaVersion": client.SchemaVersion,
+		"timestamp":     "2024-01-02T03:04:05Z",
+		"operationId":   cf.OperationID(),
+		"phase":         "Execution",
+		"type":          "Resource",
+		"stackName":     "bar",
+		"stackPath":     "bar",
+		"logicalId":     "MainBucket",
+		"physicalId":    "bar-mainbucket",
+		"resourceType":  "AWS::S3::Bucket",
+		"status":        "CREATE_FAILED",
+		"reason":        "Bucket already exists",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Got %v but expected %v", got, want)
+	}
+	for key, value := range want {
+		if got[key] != value {
+			t.Fatalf("Got %v but expected %v for %s", got[key], value, key)
+		}
+	}
+
+	var stackStatus client.Event
+	if err = json.Unmarshal([]byte(lines[1]), &stackStatus); err != nil {
+		t.Fatal(err)
+	}
+	if stackStatus.Timestamp.IsZero() || stackStatus.OperationID == "" {
+		t.Fatalf("Got %+v but expected the timestamp and operation ID to be set", stackStatus)
+	}
+}
@@ -0,0 +1,25 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/massdriver-cloud/fogmachine/pkg/eventcache"
+)
+
+// EventTracker exposes the event tracker to the client_test tests
+type EventTracker struct {
+	tracker *eventTracker
+}
+
+func NewEventTracker(stack, token string, start time.Time) *EventTracker {
+	return &EventTracker{tracker: newEventTracker(stack, token, start)}
+}
+
+func (t *EventTracker) Poll(ctx context.Context, api CloudFormationAPI) ([]eventcache.Event, error) {
+	return t.tracker.poll(ctx, api)
+}
+
+func (t *EventTracker) Events() []eventcache.Event {
+	return t.tracker.Events()
+}
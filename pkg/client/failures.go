@@ -0,0 +1,116 @@
+package client
+
+import (
+	"strings"
+	"time"
+
+	"github.com/massdriver-cloud/fogmachine/pkg/eventcache"
+	"github.com/rs/zerolog/log"
+)
+
+const stackResourceType = "AWS::CloudFormation::Stack"
+
+// Failure is a resource failure that caused a stack operation to fail, as opposed to the
+// failures CloudFormation reports for resources it cancelled because of it
+type Failure struct {
+	StackPath    string    `json:"stackPath"`
+	LogicalID    string    `json:"logicalId"`
+	ResourceType string    `json:"resourceType"`
+	PhysicalID   string    `json:"physicalId"`
+	Status       string    `json:"status"`
+	Reason       string    `json:"reason"`
+	Timestamp    time.Time `json:"timestamp"`
+}
+
+// RootCauses picks the originating failures out of the events of an operation, descending into
+// nested stacks. Events must be in chronological order. Failures of a stack, either its own events
+// or its resource in the parent stack, only summarize the failures inside it so they are only
+// returned when nothing inside that stack failed.
+func RootCauses(events []eventcache.Event) []Failure {
+	candidates := []Failure{}
+	// summarizes holds the path of the stack a candidate summarizes, empty for resource failures
+	summarizes := []string{}
+	resourceFailures := []Failure{}
+
+	for _, event := range events {
+		if !strings.HasSuffix(event.ResourceStatus, "_FAILED") || isCascadeFailure(event.Message) {
+			continue
+		}
+
+		failure := Failure{
+			StackPath:    event.StackPath,
+			LogicalID:    event.ResourceName,
+			ResourceType: event.ResourceType,
+			PhysicalID:   event.ProviderResourceID,
+			Status:       event.ResourceStatus,
+			Reason:       event.Message,
+			Timestamp:    event.Timestamp,
+		}
+
+		path := ""
+		if event.ResourceType == stackResourceType {
+			path = event.StackPath
+			if event.ProviderResourceID != event.StackID {
+				path += "/" + event.ResourceName
+			}
+		} else {
+			resourceFailures = append(resourceFailures, failure)
+		}
+
+		candidates = append(candidates, failure)
+		summarizes = append(summarizes, path)
+	}
+
+	failures := []Failure{}
+	reported := map[string]bool{}
+
+	for i, failure := range candidates {
+		path := summarizes[i]
+		if path != "" {
+			if reported[path] || hasFailureWithin(resourceFailures, path) {
+				continue
+			}
+			reported[path] = true
+		}
+		failures = append(failures, failure)
+	}
+
+	return failures
+}
+
+func hasFailureWithin(failures []Failure, stackPath string) bool {
+	for _, failure := range failures {
+		if failure.StackPath == stackPath || strings.HasPrefix(failure.StackPath, stackPath+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// isCascadeFailure reports whether CloudFormation failed the resource only because another resource failed
+func isCascadeFailure(reason string) bool {
+	reason = strings.ToLower(reason)
+	return strings.Contains(reason, "cancelled") || strings.Contains(reason, "canceled")
+}
+
+func (c Client) emitFailures(failures []Failure) {
+	if len(failures) == 0 {
+		return
+	}
+
+	log.Error().Str("phase", PhaseSummary).Int("failures", len(failures)).Msg("Stack operation failed, root causes:")
+
+	for _, failure := range failures {
+		c.Emit(Event{
+			Timestamp:    failure.Timestamp,
+			Phase:        PhaseSummary,
+			Type:         EventFailure,
+			StackPath:    failure.StackPath,
+			LogicalID:    failure.LogicalID,
+			PhysicalID:   failure.PhysicalID,
+			ResourceType: failure.ResourceType,
+			Status:       failure.Status,
+			Reason:       failure.Reason,
+		})
+	}
+}
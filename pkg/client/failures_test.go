@@ -0,0 +1,88 @@
+package client_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/massdriver-cloud/fogmachine/pkg/client"
+	"github.com/massdriver-cloud/fogmachine/pkg/eventcache"
+)
+
+const (
+	rootStackID  = "arn:aws:cloudformation:us-west-2:123456789012:stack/stack/1"
+	childStackID = "arn:aws:cloudformation:us-west-2:123456789012:stack/stack-Network-ABC/2"
+)
+
+func TestRootCauses(t *testing.T) {
+	start := time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC)
+	event := func(offset int, stackID, path, name, resourceType, physicalID, status, reason string) eventcache.Event {
+		return eventcache.Event{
+			StackID:            stackID,
+			StackPath:          path,
+			ResourceName:       name,
+			ResourceType:       resourceType,
+			ProviderResourceID: physicalID,
+			ResourceStatus:     status,
+			Message:            reason,
+			Timestamp:          start.Add(time.Duration(offset) * time.Second),
+		}
+	}
+
+	tests := map[string]struct {
+		events []eventcache.Event
+		want   []string
+	}{
+		"originating failure": {
+			events: []eventcache.Event{
+				event(0, rootStackID, "stack", "stack", "AWS::CloudFormation::Stack", rootStackID, "UPDATE_IN_PROGRESS", "User Initiated"),
+				event(1, rootStackID, "stack", "Queue", "AWS::SQS::Queue", "", "CREATE_IN_PROGRESS", ""),
+				event(2, rootStackID, "stack", "Bucket", "AWS::S3::Bucket", "", "CREATE_FAILED", "bucket already exists"),
+				event(3, rootStackID, "stack", "Queue", "AWS::SQS::Queue", "", "CREATE_FAILED", "Resource creation cancelled"),
+				event(4, rootStackID, "stack", "stack", "AWS::CloudFormation::Stack", rootStackID, "UPDATE_ROLLBACK_IN_PROGRESS", "The following resource(s) failed to create: [Bucket, Queue]."),
+			},
+			want: []string{"stack/Bucket"},
+		},
+		"stack level only": {
+			events: []eventcache.Event{
+				event(0, rootStackID, "stack", "stack", "AWS::CloudFormation::Stack", rootStackID, "DELETE_FAILED", "Role is not authorized"),
+			},
+			want: []string{"stack/stack"},
+		},
+		"nested stack": {
+			events: []eventcache.Event{
+				event(1, rootStackID, "stack", "Network", "AWS::CloudFormation::Stack", childStackID, "CREATE_IN_PROGRESS", ""),
+				event(2, childStackID, "stack/Network", "Subnet", "AWS::EC2::Subnet", "", "CREATE_FAILED", "CIDR overlaps"),
+				event(3, childStackID, "stack/Network", "stack-Network-ABC", "AWS::CloudFormation::Stack", childStackID, "CREATE_FAILED", "The following resource(s) failed to create: [Subnet]."),
+				event(4, rootStackID, "stack", "Network", "AWS::CloudFormation::Stack", childStackID, "CREATE_FAILED", "Embedded stack was not successfully created"),
+			},
+			want: []string{"stack/Network/Subnet"},
+		},
+		"nested stack failure without resource failures": {
+			events: []eventcache.Event{
+				event(1, rootStackID, "stack", "Network", "AWS::CloudFormation::Stack", "", "CREATE_FAILED", "TemplateURL must reference a valid S3 object"),
+				event(2, rootStackID, "stack", "stack", "AWS::CloudFormation::Stack", rootStackID, "ROLLBACK_IN_PROGRESS", ""),
+			},
+			want: []string{"stack/Network"},
+		},
+		"no failures": {
+			events: []eventcache.Event{
+				event(0, rootStackID, "stack", "Bucket", "AWS::S3::Bucket", "", "UPDATE_COMPLETE", ""),
+			},
+			want: []string{},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := []string{}
+			for _, failure := range client.RootCauses(tc.events) {
+				got = append(got, failure.StackPath+"/"+failure.LogicalID)
+			}
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("Got %v but expected %v", got, tc.want)
+			}
+		})
+	}
+}
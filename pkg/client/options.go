@@ -0,0 +1,173 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+)
+
+// Limits CloudFormation puts on stack options
+const (
+	maxTags                 = 50
+	maxTagKeyLength         = 128
+	maxTagValueLength       = 256
+	maxNotificationARNs     = 5
+	maxRollbackTriggers     = 5
+	maxMonitoringTimeMinute = 180
+)
+
+var (
+	roleARNPattern         = regexp.MustCompile(`^arn:aws[a-zA-Z-]*:iam::\d{12}:role/[\w+=,.@/-]+$`)
+	notificationARNPattern = regexp.MustCompile(`^arn:aws[a-zA-Z-]*:sns:[a-z0-9-]+:\d{12}:[\w-]+(\.fifo)?$`)
+	alarmARNPattern        = regexp.MustCompile(`^arn:aws[a-zA-Z-]*:cloudwatch:[a-z0-9-]+:\d{12}:alarm:.+$`)
+)
+
+// StackOptions are the stack level settings passed through to every changeset
+type StackOptions struct {
+	Capabilities          []types.Capability
+	Tags                  map[string]string
+	RoleARN               string
+	NotificationARNs      []string
+	RollbackConfiguration *types.RollbackConfiguration
+	IncludeNestedStacks   bool
+}
+
+// WithDefaultTags returns the options with the tags added where they don't already set the key
+func (o StackOptions) WithDefaultTags(tags map[string]string) StackOptions {
+	if len(tags) == 0 {
+		return o
+	}
+
+	merged := make(map[string]string, len(o.Tags)+len(tags))
+	for key, value := range tags {
+		merged[key] = value
+	}
+	for key, value := range o.Tags {
+		merged[key] = value
+	}
+	o.Tags = merged
+
+	return o
+}
+
+// Validate checks the options against CloudFormation's rules so mistakes are caught before any API call
+func (o StackOptions) Validate() error {
+	errs := []error{}
+
+	for _, capability := range o.Capabilities {
+		if !isKnownCapability(capability) {
+			errs = append(errs, fmt.Errorf("unknown capability %q, expected one of %v", capability, capability.Values()))
+		}
+	}
+
+	errs = append(errs, validateTags(o.Tags)...)
+
+	if o.RoleARN != "" && !roleARNPattern.MatchString(o.RoleARN) {
+		errs = append(errs, fmt.Errorf("role ARN %q is not an IAM role ARN", o.RoleARN))
+	}
+
+	if len(o.NotificationARNs) > maxNotificationARNs {
+		errs = append(errs, fmt.Errorf("at most %d notification ARNs are allowed, got %d", maxNotificationARNs, len(o.NotificationARNs)))
+	}
+
+	for _, arn := range o.NotificationARNs {
+		if !notificationARNPattern.MatchString(arn) {
+			errs = append(errs, fmt.Errorf("notification ARN %q is not an SNS topic ARN", arn))
+		}
+	}
+
+	if o.RollbackConfiguration != nil {
+		errs = append(errs, validateRollbackConfiguration(o.RollbackConfiguration)...)
+	}
+
+	return errors.Join(errs...)
+}
+
+func (o StackOptions) applyTo(input *cloudformation.CreateChangeSetInput) {
+	input.Capabilities = o.Capabilities
+	input.NotificationARNs = o.NotificationARNs
+	input.RollbackConfiguration = o.RollbackConfiguration
+
+	if o.RoleARN != "" {
+		input.RoleARN = aws.String(o.RoleARN)
+	}
+
+	if o.IncludeNestedStacks {
+		input.IncludeNestedStacks = aws.Bool(true)
+	}
+
+	keys := make([]string, 0, len(o.Tags))
+	for key := range o.Tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		input.Tags = append(input.Tags, types.Tag{Key: aws.String(key), Value: aws.String(o.Tags[key])})
+	}
+}
+
+func isKnownCapability(capability types.Capability) bool {
+	for _, known := range capability.Values() {
+		if capability == known {
+			return true
+		}
+	}
+	return false
+}
+
+func validateTags(tags map[string]string) []error {
+	errs := []error{}
+
+	if len(tags) > maxTags {
+		errs = append(errs, fmt.Errorf("at most %d tags are allowed, got %d", maxTags, len(tags)))
+	}
+
+	for key, value := range tags {
+		switch {
+		case key == "":
+			errs = append(errs, errors.New("tag keys must not be empty"))
+		case len(key) > maxTagKeyLength:
+			errs = append(errs, fmt.Errorf("tag key %q is longer than %d characters", key, maxTagKeyLength))
+		case strings.HasPrefix(strings.ToLower(key), "aws:"):
+			errs = append(errs, fmt.Errorf("tag key %q uses the reserved aws: prefix", key))
+		}
+
+		if len(value) > maxTagValueLength {
+			errs = append(errs, fmt.Errorf("value of tag %q is longer than %d characters", key, maxTagValueLength))
+		}
+	}
+
+	return errs
+}
+
+func validateRollbackConfiguration(config *types.RollbackConfiguration) []error {
+	errs := []error{}
+
+	if minutes := aws.ToInt32(config.MonitoringTimeInMinutes); minutes < 0 || minutes > maxMonitoringTimeMinute {
+		errs = append(errs, fmt.Errorf("rollback monitoring time must be between 0 and %d minutes, got %d", maxMonitoringTimeMinute, minutes))
+	}
+
+	if len(config.RollbackTriggers) > maxRollbackTriggers {
+		errs = append(errs, fmt.Errorf("at most %d rollback triggers are allowed, got %d", maxRollbackTriggers, len(config.RollbackTriggers)))
+	}
+
+	for _, trigger := range config.RollbackTriggers {
+		arn := aws.ToString(trigger.Arn)
+		if !alarmARNPattern.MatchString(arn) {
+			errs = append(errs, fmt.Errorf("rollback trigger %q is not a CloudWatch alarm ARN", arn))
+		}
+
+		if triggerType := aws.ToString(trigger.Type); triggerType != "AWS::CloudWatch::Alarm" && triggerType != "AWS::CloudWatch::CompositeAlarm" {
+			errs = append(errs, fmt.Errorf("rollback trigger %q has type %q, expected AWS::CloudWatch::Alarm or AWS::CloudWatch::CompositeAlarm", arn, triggerType))
+		}
+	}
+
+	return errs
+}
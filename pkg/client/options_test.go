@@ -0,0 +1,89 @@
+package client_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/massdriver-cloud/fogmachine/pkg/client"
+)
+
+func TestStackOptionsValidate(t *testing.T) {
+	tests := map[string]struct {
+		options client.StackOptions
+		wantErr string
+	}{
+		"valid": {
+			options: client.StackOptions{
+				Capabilities:     []types.Capability{types.CapabilityCapabilityIam, types.CapabilityCapabilityAutoExpand},
+				Tags:             map[string]string{"team": "platform"},
+				RoleARN:          "arn:aws:iam::123456789012:role/cfn-deploy",
+				NotificationARNs: []string{"arn:aws:sns:us-west-2:123456789012:stack-events"},
+				RollbackConfiguration: &types.RollbackConfiguration{
+					MonitoringTimeInMinutes: aws.Int32(10),
+					RollbackTriggers: []types.RollbackTrigger{{
+						Arn:  aws.String("arn:aws:cloudwatch:us-west-2:123456789012:alarm:errors"),
+						Type: aws.String("AWS::CloudWatch::Alarm"),
+					}},
+				},
+			},
+		},
+		"capability": {
+			options: client.StackOptions{Capabilities: []types.Capability{"CAPABILITY_ADMIN"}},
+			wantErr: "unknown capability",
+		},
+		"reserved tag": {
+			options: client.StackOptions{Tags: map[string]string{"aws:cloudformation:stack-name": "x"}},
+			wantErr: "reserved aws: prefix",
+		},
+		"role": {
+			options: client.StackOptions{RoleARN: "arn:aws:iam::123456789012:user/deployer"},
+			wantErr: "not an IAM role ARN",
+		},
+		"notification": {
+			options: client.StackOptions{NotificationARNs: []string{"arn:aws:sqs:us-west-2:123456789012:queue"}},
+			wantErr: "not an SNS topic ARN",
+		},
+		"monitoring time": {
+			options: client.StackOptions{RollbackConfiguration: &types.RollbackConfiguration{MonitoringTimeInMinutes: aws.Int32(200)}},
+			wantErr: "between 0 and 180",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := tc.options.Validate()
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("Got %v but expected an error containing %s", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestStackOptionsWithDefaultTags(t *testing.T) {
+	options := client.StackOptions{Tags: map[string]string{"team": "platform", "env": "prod"}}
+
+	got := options.WithDefaultTags(map[string]string{"env": "dev", "owner": "infra"})
+	want := map[string]string{"team": "platform", "env": "prod", "owner": "infra"}
+
+	if len(got.Tags) != len(want) {
+		t.Fatalf("Got %v but expected %v", got.Tags, want)
+	}
+	for key, value := range want {
+		if got.Tags[key] != value {
+			t.Fatalf("Got %v but expected %v", got.Tags, want)
+		}
+	}
+
+	if options.Tags["owner"] != "" {
+		t.Fatalf("Got %v but expected the original options unchanged", options.Tags)
+	}
+}
@@ -0,0 +1,365 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/massdriver-cloud/fogmachine/pkg/redact"
+	"github.com/rs/zerolog/log"
+)
+
+// EventSink receives every event the client emits. Send is called from the watcher goroutines so sinks must be
+// safe for concurrent use. Close flushes anything buffered and releases the sink.
+type EventSink interface {
+	Send(event Event) error
+	Close() error
+}
+
+// ConsoleSink logs events for humans, it's the sink clients start with
+type ConsoleSink struct{}
+
+// Send logs the event
+func (ConsoleSink) Send(event Event) error {
+	event.log()
+	return nil
+}
+
+// Close does nothing, the logger isn't owned by the sink
+func (ConsoleSink) Close() error {
+	return nil
+}
+
+// FuncSink calls the function with each event, from the watcher goroutines
+type FuncSink func(Event)
+
+// Send calls the function
+func (f FuncSink) Send(event Event) error {
+	f(event)
+	return nil
+}
+
+// Close does nothing
+func (FuncSink) Close() error {
+	return nil
+}
+
+// ChannelSink sends each event on ch. Sends block until the event is received so ch must be drained, or
+// buffered, while the operation runs. The channel isn't closed by the sink.
+func ChannelSink(ch chan<- Event) FuncSink {
+	return func(event Event) {
+		ch <- event
+	}
+}
+
+// NDJSONSink writes each event as a line of JSON
+type NDJSONSink struct {
+	mu     sync.Mutex
+	out    io.Writer
+	closer io.Closer
+}
+
+// NewNDJSONSink writes events to w, which isn't closed with the sink
+func NewNDJSONSink(w io.Writer) *NDJSONSink {
+	return &NDJSONSink{out: w}
+}
+
+// NewFileSink creates or truncates the file at path and writes events to it
+func NewFileSink(path string) (*NDJSONSink, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create events file: %w", err)
+	}
+
+	return &NDJSONSink{out: file, closer: file}, nil
+}
+
+// Send writes the event as a single line
+func (s *NDJSONSink) Send(event Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return json.NewEncoder(s.out).Encode(event)
+}
+
+// Close closes the file the sink writes to
+func (s *NDJSONSink) Close() error {
+	if s.closer == nil {
+		return nil
+	}
+	return s.closer.Close()
+}
+
+// redactingSink masks secrets in the fields that carry CloudFormation text before passing the event on
+type redactingSink struct {
+	sink     EventSink
+	redactor *redact.Redactor
+}
+
+// Send redacts the event and sends it to the wrapped sink
+func (s redactingSink) Send(event Event) error {
+	for _, field := range []*string{
+		&event.StackPath, &event.ChangesetID, &event.LogicalID, &event.PhysicalID, &event.ResourceType,
+		&event.Status, &event.Category, &event.Reason, &event.Action, &event.Replacement, &event.Before,
+		&event.After,
+	} {
+		*field = s.redactor.String(*field)
+	}
+
+	return s.sink.Send(event)
+}
+
+// Close closes the wrapped sink
+func (s redactingSink) Close() error {
+	return s.sink.Close()
+}
+
+// MultiSink sends every event to each of its sinks
+type MultiSink []EventSink
+
+// Send sends the event to every sink, a failing sink doesn't stop the others
+func (m MultiSink) Send(event Event) error {
+	errs := make([]error, 0, len(m))
+	for _, sink := range m {
+		errs = append(errs, sink.Send(event))
+	}
+	return errors.Join(errs...)
+}
+
+// Close closes every sink
+func (m MultiSink) Close() error {
+	errs := make([]error, 0, len(m))
+	for _, sink := range m {
+		errs = append(errs, sink.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// WebhookSecretEnv is read for the webhook secret when --webhook-secret isn't set, to keep it out of the process list
+const WebhookSecretEnv = "FOGMACHINE_WEBHOOK_SECRET"
+
+// SignatureHeader carries the hex HMAC-SHA256 of the webhook body, keyed with the webhook secret
+const SignatureHeader = "X-Fogmachine-Signature"
+
+// WebhookOptions configures a WebhookSink. Zero values use the defaults.
+type WebhookOptions struct {
+	URL string
+	// Secret signs each request in SignatureHeader, requests aren't signed without one
+	Secret string
+	// BatchSize is the most events sent in one request, 20 by default
+	BatchSize int
+	// FlushInterval is how long events wait for a batch to fill before they're sent anyway, 2s by default
+	FlushInterval time.Duration
+	// MaxRetries is how many times a failed request is retried, 3 by default and none when negative
+	MaxRetries int
+	// RetryDelay is the wait before the first retry, it doubles each retry. 500ms by default.
+	RetryDelay time.Duration
+	HTTPClient *http.Client
+}
+
+// WebhookBatch is the body of each webhook request
+type WebhookBatch struct {
+	SchemaVersion string  `json:"schemaVersion"`
+	Events        []Event `json:"events"`
+}
+
+// WebhookSink POSTs batches of events as JSON to a URL
+type WebhookSink struct {
+	opts WebhookOptions
+
+	mu      sync.Mutex
+	pending []Event
+	// closed refuses events sent after Close, nothing would deliver them
+	closed bool
+	// sending keeps batches in order
+	sending sync.Mutex
+
+	// full wakes the flusher once a batch fills, so Send never waits on the webhook
+	full chan struct{}
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// NewWebhookSink starts a sink that sends events once a batch fills or the flush interval passes
+func NewWebhookSink(opts WebhookOptions) *WebhookSink {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 20
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = 2 * time.Second
+	}
+	if opts.MaxRetries < 0 {
+		opts.MaxRetries = 0
+	} else if opts.MaxRetries == 0 {
+		opts.MaxRetries = 3
+	}
+	if opts.RetryDelay <= 0 {
+		opts.RetryDelay = 500 * time.Millisecond
+	}
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	s := &WebhookSink{
+		opts: opts,
+		full: make(chan struct{}, 1),
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	go s.run()
+
+	return s
+}
+
+// Send queues the event and wakes the background flusher once a batch is full, it never sends itself. Events sent
+// after Close are dropped with ErrSinkClosed.
+func (s *WebhookSink) Send(event Event) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return ErrSinkClosed
+	}
+	s.pending = append(s.pending, event)
+	full := len(s.pending) >= s.opts.BatchSize
+	s.mu.Unlock()
+
+	if full {
+		select {
+		case s.full <- struct{}{}:
+		default:
+			// The flusher is already due to run
+		}
+	}
+	return nil
+}
+
+// Flush sends the queued events now, in batches of at most BatchSize. Every batch is attempted and the
+// first error is returned.
+func (s *WebhookSink) Flush() error {
+	s.sending.Lock()
+	defer s.sending.Unlock()
+
+	s.mu.Lock()
+	pending := s.pending
+	s.pending = nil
+	s.mu.Unlock()
+
+	var err error
+	for len(pending) > 0 {
+		size := s.opts.BatchSize
+		if size > len(pending) {
+			size = len(pending)
+		}
+
+		if postErr := s.post(pending[:size]); postErr != nil && err == nil {
+			err = postErr
+		}
+		pending = pending[size:]
+	}
+
+	return err
+}
+
+// Close stops the flush timer and sends the queued events, later events are refused
+func (s *WebhookSink) Close() error {
+	s.once.Do(func() {
+		s.mu.Lock()
+		s.closed = true
+		s.mu.Unlock()
+
+		close(s.stop)
+		<-s.done
+	})
+	return s.Flush()
+}
+
+func (s *WebhookSink) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.opts.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-s.full:
+			s.flushInBackground()
+		case <-ticker.C:
+			s.flushInBackground()
+		}
+	}
+}
+
+func (s *WebhookSink) flushInBackground() {
+	if err := s.Flush(); err != nil {
+		log.Warn().Err(err).Msg("unable to send events to the webhook")
+	}
+}
+
+// post sends the batch, retrying connection errors, throttling and server errors
+func (s *WebhookSink) post(batch []Event) error {
+	body, err := json.Marshal(WebhookBatch{SchemaVersion: SchemaVersion, Events: batch})
+	if err != nil {
+		return err
+	}
+
+	delay := s.opts.RetryDelay
+	for attempt := 0; ; attempt++ {
+		retry, postErr := s.postOnce(body)
+		if postErr == nil {
+			return nil
+		}
+		if !retry || attempt >= s.opts.MaxRetries {
+			return fmt.Errorf("unable to send %d events to the webhook: %w", len(batch), postErr)
+		}
+
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+func (s *WebhookSink) postOnce(body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, s.opts.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.opts.Secret != "" {
+		req.Header.Set(SignatureHeader, "sha256="+Sign(body, s.opts.Secret))
+	}
+
+	resp, err := s.opts.HTTPClient.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	switch {
+	case resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("webhook returned %s", resp.Status)
+	default:
+		return false, fmt.Errorf("webhook returned %s", resp.Status)
+	}
+}
+
+// Sign returns the hex HMAC-SHA256 of body keyed with secret, as sent in SignatureHeader after "sha256="
+func Sign(body []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,187 @@
+package client_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/massdriver-cloud/fogmachine/pkg/client"
+)
+
+func TestWebhookSink(t *testing.T) {
+	var mu sync.Mutex
+	batches := []client.WebhookBatch{}
+	attempts := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		attempts++
+		// The first request fails so it's retried
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		if got, want := r.Header.Get(client.SignatureHeader), "sha256="+client.Sign(body, "s3cret"); got != want {
+			t.Errorf("Got %s but expected %s", got, want)
+		}
+
+		var batch client.WebhookBatch
+		if err := json.Unmarshal(body, &batch); err != nil {
+			t.Error(err)
+		}
+		batches = append(batches, batch)
+	}))
+	defer server.Close()
+
+	sink := client.NewWebhookSink(client.WebhookOptions{
+		URL:           server.URL,
+		Secret:        "s3cret",
+		BatchSize:     2,
+		FlushInterval: time.Hour,
+		RetryDelay:    time.Millisecond,
+	})
+
+	for _, id := range []string{"First", "Second", "Third"} {
+		if err := sink.Send(client.Event{Type: client.EventResource, LogicalID: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if attempts != 3 {
+		t.Fatalf("Got %d attempts but expected 3", attempts)
+	}
+
+	if len(batches) != 2 || len(batches[0].Events) != 2 || len(batches[1].Events) != 1 {
+		t.Fatalf("Got %+v but expected batches of 2 and 1 events", batches)
+	}
+
+	if batches[1].Events[0].LogicalID != "Third" {
+		t.Fatalf("Got %s but expected %s", batches[1].Events[0].LogicalID, "Third")
+	}
+}
+
+func TestWebhookSinkSendAfterClose(t *testing.T) {
+	var mu sync.Mutex
+	requests := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests++
+	}))
+	defer server.Close()
+
+	sink := client.NewWebhookSink(client.WebhookOptions{URL: server.URL, FlushInterval: time.Hour})
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := sink.Send(client.Event{Type: client.EventResource, LogicalID: "Late"}); !errors.Is(err, client.ErrSinkClosed) {
+		t.Fatalf("Got %v but expected %v", err, client.ErrSinkClosed)
+	}
+
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if requests != 0 {
+		t.Fatalf("Got %d requests but expected the late event to be dropped", requests)
+	}
+}
+
+func TestWebhookSinkStalledServer(t *testing.T) {
+	release := make(chan struct{})
+	var mu sync.Mutex
+	received := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		<-release
+
+		var batch client.WebhookBatch
+		if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+			t.Error(err)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		received += len(batch.Events)
+	}))
+	defer server.Close()
+
+	sink := client.NewWebhookSink(client.WebhookOptions{URL: server.URL, BatchSize: 1, FlushInterval: time.Hour})
+
+	// Every Send fills a batch while the webhook is stalled, none of them may wait on it
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		if err := sink.Send(client.Event{Type: client.EventResource}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("Got %s but expected Send to return without waiting on the webhook", elapsed)
+	}
+
+	close(release)
+
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if received != 10 {
+		t.Fatalf("Got %d events but expected 10", received)
+	}
+}
+
+func TestWebhookSinkClientError(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	sink := client.NewWebhookSink(client.WebhookOptions{URL: server.URL, RetryDelay: time.Millisecond})
+	if err := sink.Send(client.Event{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := sink.Close(); err == nil {
+		t.Fatalf("Got nil but expected an error")
+	}
+
+	if attempts != 1 {
+		t.Fatalf("Got %d attempts but expected 1, client errors aren't retried", attempts)
+	}
+}
+
+func TestMultiSink(t *testing.T) {
+	var first, second bytes.Buffer
+	sink := client.MultiSink{client.NewNDJSONSink(&first), client.NewNDJSONSink(&second)}
+
+	if err := sink.Send(client.Event{Type: client.EventStackStatus}); err != nil {
+		t.Fatal(err)
+	}
+
+	if first.Len() == 0 || first.String() != second.String() {
+		t.Fatalf("Got %q and %q but expected the same event in both", first.String(), second.String())
+	}
+}
@@ -0,0 +1,153 @@
+package client
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+)
+
+// StatusCategory groups CloudFormation stack statuses by what they mean for the operation that produced them
+type StatusCategory int
+
+const (
+	// StatusInProgress means the stack is still changing
+	StatusInProgress StatusCategory = iota
+	// StatusSuccess means the operation completed as requested
+	StatusSuccess
+	// StatusRolledBack means the operation failed and CloudFormation rolled the stack back cleanly
+	StatusRolledBack
+	// StatusFailed means the operation or its rollback failed and the stack needs attention
+	StatusFailed
+)
+
+// StackStatusError is returned when a stack operation ends in a rolled back or failed status
+type StackStatusError struct {
+	Status   types.StackStatus
+	Reason   string
+	Failures []Failure
+}
+
+func (e *StackStatusError) Error() string {
+	msg := fmt.Sprintf("stack finished in status %s", e.Status)
+	if e.Reason != "" {
+		msg += ": " + e.Reason
+	}
+
+	if len(e.Failures) > 0 {
+		msg += fmt.Sprintf(" (root cause: %s: %s)", e.Failures[0].LogicalID, e.Failures[0].Reason)
+	}
+
+	return msg
+}
+
+// Category returns the category of the status the stack finished in
+func (e *StackStatusError) Category() StatusCategory {
+	return CategorizeStackStatus(e.Status)
+}
+
+func (s StatusCategory) String() string {
+	switch s {
+	case StatusInProgress:
+		return "InProgress"
+	case StatusSuccess:
+		return "Success"
+	case StatusRolledBack:
+		return "RolledBack"
+	case StatusFailed:
+		return "Failed"
+	default:
+		return "Unknown"
+	}
+}
+
+// CategorizeStackStatus maps every CloudFormation stack status to a category. Unknown statuses are
+// treated as in progress so a new status added by AWS never ends a watch early.
+func CategorizeStackStatus(status types.StackStatus) StatusCategory {
+	switch status {
+	case types.StackStatusCreateComplete,
+		types.StackStatusUpdateComplete,
+		types.StackStatusDeleteComplete,
+		types.StackStatusImportComplete:
+		return StatusSuccess
+	case types.StackStatusRollbackComplete,
+		types.StackStatusUpdateRollbackComplete,
+		types.StackStatusImportRollbackComplete:
+		return StatusRolledBack
+	case types.StackStatusCreateFailed,
+		types.StackStatusUpdateFailed,
+		types.StackStatusDeleteFailed,
+		types.StackStatusRollbackFailed,
+		types.StackStatusUpdateRollbackFailed,
+		types.StackStatusImportRollbackFailed:
+		return StatusFailed
+	case types.StackStatusCreateInProgress,
+		types.StackStatusRollbackInProgress,
+		types.StackStatusDeleteInProgress,
+		types.StackStatusUpdateInProgress,
+		types.StackStatusUpdateCompleteCleanupInProgress,
+		types.StackStatusUpdateRollbackInProgress,
+		types.StackStatusUpdateRollbackCompleteCleanupInProgress,
+		types.StackStatusReviewInProgress,
+		types.StackStatusImportInProgress,
+		types.StackStatusImportRollbackInProgress:
+		return StatusInProgress
+	default:
+		return StatusInProgress
+	}
+}
+
+// IsTerminalStackStatus reports whether the stack has stopped changing
+func IsTerminalStackStatus(status types.StackStatus) bool {
+	return CategorizeStackStatus(status) != StatusInProgress
+}
+
+// updatableStatuses are the statuses a changeset can be created in, a stack in review only has changesets
+var updatableStatuses = map[types.StackStatus]bool{
+	types.StackStatusCreateComplete:         true,
+	types.StackStatusUpdateComplete:         true,
+	types.StackStatusUpdateRollbackComplete: true,
+	types.StackStatusImportComplete:         true,
+	types.StackStatusImportRollbackComplete: true,
+	types.StackStatusReviewInProgress:       true,
+}
+
+// Updatable reports whether a changeset can be created for a stack in the status
+func Updatable(status types.StackStatus) bool {
+	return updatableStatuses[status]
+}
+
+func isTerminalChangeSetStatus(status types.ChangeSetStatus) bool {
+	switch status {
+	case types.ChangeSetStatusCreateComplete,
+		types.ChangeSetStatusFailed,
+		types.ChangeSetStatusDeleteComplete,
+		types.ChangeSetStatusDeleteFailed:
+		return true
+	case types.ChangeSetStatusCreatePending,
+		types.ChangeSetStatusCreateInProgress,
+		types.ChangeSetStatusDeletePending,
+		types.ChangeSetStatusDeleteInProgress:
+		return false
+	default:
+		return false
+	}
+}
+
+// stackResult turns the status a stack finished in into the error for the operation, if any
+func stackResult(stack *types.Stack) error {
+	category := CategorizeStackStatus(stack.StackStatus)
+	if category == StatusSuccess {
+		return nil
+	}
+
+	if category == StatusInProgress {
+		return ErrTimeout
+	}
+
+	reason := ""
+	if stack.StackStatusReason != nil {
+		reason = *stack.StackStatusReason
+	}
+
+	return &StackStatusError{Status: stack.StackStatus, Reason: reason}
+}
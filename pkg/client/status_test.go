@@ -0,0 +1,84 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/massdriver-cloud/fogmachine/pkg/client"
+	"github.com/massdriver-cloud/fogmachine/pkg/testing/fake"
+)
+
+func TestCategorizeStackStatus(t *testing.T) {
+	tests := map[types.StackStatus]client.StatusCategory{
+		types.StackStatusCreateComplete:                          client.StatusSuccess,
+		types.StackStatusUpdateComplete:                          client.StatusSuccess,
+		types.StackStatusDeleteComplete:                          client.StatusSuccess,
+		types.StackStatusImportComplete:                          client.StatusSuccess,
+		types.StackStatusRollbackComplete:                        client.StatusRolledBack,
+		types.StackStatusUpdateRollbackComplete:                  client.StatusRolledBack,
+		types.StackStatusImportRollbackComplete:                  client.StatusRolledBack,
+		types.StackStatusCreateFailed:                            client.StatusFailed,
+		types.StackStatusDeleteFailed:                            client.StatusFailed,
+		types.StackStatusRollbackFailed:                          client.StatusFailed,
+		types.StackStatusUpdateRollbackFailed:                    client.StatusFailed,
+		types.StackStatusImportRollbackFailed:                    client.StatusFailed,
+		types.StackStatusUpdateInProgress:                        client.StatusInProgress,
+		types.StackStatusUpdateCompleteCleanupInProgress:         client.StatusInProgress,
+		types.StackStatusUpdateRollbackCompleteCleanupInProgress: client.StatusInProgress,
+		types.StackStatusReviewInProgress:                        client.StatusInProgress,
+		"SOME_NEW_STATUS":                                        client.StatusInProgress,
+	}
+
+	for status, want := range tests {
+		if got := client.CategorizeStackStatus(status); got != want {
+			t.Errorf("Got %s but expected %s for %s", got, want, status)
+		}
+	}
+}
+
+// failUpdate runs an update of the stack that rolls back after resource fails with reason
+func failUpdate(t *testing.T, cf *fake.CloudFormation, resource, reason string) error {
+	ctx := context.Background()
+	cf.FailResource(resource, reason)
+
+	c, err := client.New(ctx, client.Config{StackName: "bar", Timeout: 5 * time.Second, PollInterval: time.Millisecond, CloudFormation: cf, Events: client.FuncSink(func(client.Event) {})})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = c.CreateChangeset(ctx, []byte(bucketTemplate+"  "+resource+":\n    Type: AWS::S3::Bucket\n"), nil, client.StackOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	return c.ExecuteChangeSet(ctx)
+}
+
+func TestExecuteChangeSetRollback(t *testing.T) {
+	cf := fake.New()
+	if err := cf.AddStack("bar", types.StackStatusCreateComplete, bucketTemplate); err != nil {
+		t.Fatal(err)
+	}
+
+	// The failure of a previous deploy isn't a root cause of this one
+	if err := failUpdate(t, cf, "Logs", "A failure from a previous deploy"); err == nil {
+		t.Fatal("Expected the previous deploy to fail")
+	}
+
+	err := failUpdate(t, cf, "MainBucket", "Access Denied")
+
+	var statusErr *client.StackStatusError
+	if !errors.As(err, &statusErr) {
+		t.Fatalf("Got %v but expected a StackStatusError", err)
+	}
+
+	if statusErr.Category() != client.StatusRolledBack {
+		t.Fatalf("Got %s but expected %s", statusErr.Category(), client.StatusRolledBack)
+	}
+
+	if len(statusErr.Failures) != 1 || statusErr.Failures[0].Reason != "Access Denied" {
+		t.Fatalf("Got failures %v but expected the MainBucket failure", statusErr.Failures)
+	}
+}
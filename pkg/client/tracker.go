@@ -0,0 +1,173 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/massdriver-cloud/fogmachine/pkg/eventcache"
+	"github.com/rs/zerolog/log"
+)
+
+// clockSkew is how far before the local operation start we keep paging, so events are not lost
+// when the local clock runs ahead of CloudFormation's
+const clockSkew = 5 * time.Minute
+
+// eventTracker follows the stack events of a single operation. Events are scoped to the operation
+// by its ClientRequestToken. Without a token the tracker adopts the token of the first stack level
+// *_IN_PROGRESS event after the operation started. Nested stacks found in the events get a tracker
+// of their own so their events are followed too.
+type eventTracker struct {
+	stack    string
+	path     string
+	token    string
+	start    time.Time
+	seen     *eventcache.EventCache
+	events   []eventcache.Event
+	children []*eventTracker
+}
+
+func newEventTracker(stack, token string, start time.Time) *eventTracker {
+	return &eventTracker{
+		stack:  stack,
+		path:   stack,
+		token:  token,
+		start:  start,
+		seen:   eventcache.New(),
+		events: []eventcache.Event{},
+	}
+}
+
+// newOperationToken returns a ClientRequestToken unique to a single stack operation
+func newOperationToken() string {
+	return fmt.Sprintf("fogmachine-%d", time.Now().UnixNano())
+}
+
+// poll returns the new events of the operation in this stack and its nested stacks, oldest first
+func (t *eventTracker) poll(ctx context.Context, api CloudFormationAPI) ([]eventcache.Event, error) {
+	events, err := t.pollStack(ctx, api)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, child := range t.children {
+		childEvents, childErr := child.poll(ctx, api)
+		if childErr != nil {
+			// A nested stack failing to report shouldn't stop us following the parent
+			log.Warn().Err(childErr).Str("phase", "Execution").Str("stackPath", child.path).Msg("unable to poll nested stack events")
+			continue
+		}
+		events = append(events, childEvents...)
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Timestamp.Before(events[j].Timestamp)
+	})
+
+	t.events = append(t.events, events...)
+
+	return events, nil
+}
+
+// Events returns every event of the operation seen so far, oldest first
+func (t *eventTracker) Events() []eventcache.Event {
+	return t.events
+}
+
+// pollStack pages through the events of this stack only, until it reaches events already seen or
+// older than the operation
+func (t *eventTracker) pollStack(ctx context.Context, api CloudFormationAPI) ([]eventcache.Event, error) {
+	params := &cloudformation.DescribeStackEventsInput{
+		StackName: aws.String(t.stack),
+	}
+
+	// DescribeStackEvents returns the newest events first
+	unseen := []types.StackEvent{}
+	boundary := t.start.Add(-clockSkew)
+
+	for {
+		result, err := api.DescribeStackEvents(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+
+		done := false
+		for _, event := range result.StackEvents {
+			if t.seen.EventExists(aws.ToString(event.EventId)) || aws.ToTime(event.Timestamp).Before(boundary) {
+				done = true
+				break
+			}
+			unseen = append(unseen, event)
+		}
+
+		if done || result.NextToken == nil {
+			break
+		}
+
+		params.NextToken = result.NextToken
+	}
+
+	events := []eventcache.Event{}
+	for i := len(unseen) - 1; i >= 0; i-- {
+		event := unseen[i]
+		e := t.seen.EventFromStack(event, "Resource")
+		e.StackPath = t.path
+		t.seen.AddEvent(aws.ToString(event.EventId), e)
+
+		if !t.inOperation(event) {
+			continue
+		}
+
+		t.discoverChild(event)
+		events = append(events, e)
+	}
+
+	return events, nil
+}
+
+// discoverChild starts tracking the nested stack the event is about, if it is one we haven't seen
+func (t *eventTracker) discoverChild(event types.StackEvent) {
+	physicalID := aws.ToString(event.PhysicalResourceId)
+	if aws.ToString(event.ResourceType) != stackResourceType || physicalID == "" || isStackEvent(event) {
+		return
+	}
+
+	for _, child := range t.children {
+		if child.stack == physicalID {
+			return
+		}
+	}
+
+	child := newEventTracker(physicalID, "", t.start)
+	child.path = t.path + "/" + aws.ToString(event.LogicalResourceId)
+	t.children = append(t.children, child)
+
+	log.Debug().Str("phase", "Execution").Str("stackPath", child.path).Msg("following nested stack")
+}
+
+func (t *eventTracker) inOperation(event types.StackEvent) bool {
+	token := aws.ToString(event.ClientRequestToken)
+
+	if t.token == "" && token != "" && isStackEvent(event) &&
+		strings.HasSuffix(string(event.ResourceStatus), "_IN_PROGRESS") &&
+		!aws.ToTime(event.Timestamp).Before(t.start) {
+		t.token = token
+	}
+
+	if t.token != "" && token != "" {
+		return token == t.token
+	}
+
+	return !aws.ToTime(event.Timestamp).Before(t.start)
+}
+
+// isStackEvent reports whether the event is about the stack itself rather than one of its resources
+func isStackEvent(event types.StackEvent) bool {
+	return aws.ToString(event.ResourceType) == stackResourceType &&
+		aws.ToString(event.PhysicalResourceId) == aws.ToString(event.StackId)
+}
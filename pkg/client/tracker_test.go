@@ -0,0 +1,221 @@
+package client_test
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/massdriver-cloud/fogmachine/pkg/client"
+)
+
+const stackID = "arn:aws:cloudformation:us-west-2:123456789012:stack/bar/1"
+
+// pagedEvents serves the events of each stack newest first, pageSize at a time
+type pagedEvents struct {
+	client.CloudFormationAPI
+	pageSize int
+	// events are the events of each stack, oldest first
+	events map[string][]types.StackEvent
+	calls  int
+}
+
+func (p *pagedEvents) DescribeStackEvents(_ context.Context, params *cloudformation.DescribeStackEventsInput, _ ...func(*cloudformation.Options)) (*cloudformation.DescribeStackEventsOutput, error) {
+	p.calls++
+
+	events := p.events[aws.ToString(params.StackName)]
+	end := len(events)
+	if params.NextToken != nil {
+		end, _ = strconv.Atoi(aws.ToString(params.NextToken))
+	}
+
+	start := 0
+	if end > p.pageSize {
+		start = end - p.pageSize
+	}
+
+	output := &cloudformation.DescribeStackEventsOutput{}
+	for i := end - 1; i >= start; i-- {
+		output.StackEvents = append(output.StackEvents, events[i])
+	}
+	if start > 0 {
+		output.NextToken = aws.String(strconv.Itoa(start))
+	}
+
+	return output, nil
+}
+
+func stackEvent(id, status, token string, at time.Time) types.StackEvent {
+	event := types.StackEvent{
+		EventId:            aws.String(id),
+		StackId:            aws.String(stackID),
+		StackName:          aws.String("bar"),
+		LogicalResourceId:  aws.String("bar"),
+		PhysicalResourceId: aws.String(stackID),
+		ResourceType:       aws.String("AWS::CloudFormation::Stack"),
+		ResourceStatus:     types.ResourceStatus(status),
+		Timestamp:          aws.Time(at),
+	}
+	if token != "" {
+		event.ClientRequestToken = aws.String(token)
+	}
+	return event
+}
+
+func resourceEvent(id, logicalID, status, token string, at time.Time) types.StackEvent {
+	event := stackEvent(id, status, token, at)
+	event.LogicalResourceId = aws.String(logicalID)
+	event.PhysicalResourceId = aws.String("bar-" + logicalID)
+	event.ResourceType = aws.String("AWS::S3::Bucket")
+	return event
+}
+
+func names(t *testing.T, tracker *client.EventTracker, api client.CloudFormationAPI) []string {
+	t.Helper()
+
+	events, err := tracker.Poll(context.Background(), api)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := []string{}
+	for _, event := range events {
+		got = append(got, event.ResourceName+" "+event.ResourceStatus)
+	}
+	return got
+}
+
+func TestEventTracker(t *testing.T) {
+	start := time.Date(2024, 1, 2, 3, 0, 0, 0, time.UTC)
+
+	t.Run("pages until events older than the clock skew", func(t *testing.T) {
+		events := []types.StackEvent{
+			resourceEvent("old", "Bucket", "CREATE_FAILED", "", start.Add(-time.Hour)),
+			resourceEvent("skewed", "Bucket", "UPDATE_FAILED", "", start.Add(-10*time.Minute)),
+		}
+		events = append(events, stackEvent("1", "UPDATE_IN_PROGRESS", "fogmachine-1", start.Add(time.Second)))
+		for i := 2; i < 7; i++ {
+			events = append(events, resourceEvent(strconv.Itoa(i), fmt.Sprintf("Bucket%d", i), "UPDATE_COMPLETE", "fogmachine-1", start.Add(time.Duration(i)*time.Second)))
+		}
+
+		api := &pagedEvents{pageSize: 2, events: map[string][]types.StackEvent{"bar": events}}
+		tracker := client.NewEventTracker("bar", "fogmachine-1", start)
+
+		got := names(t, tracker, api)
+		want := []string{"bar UPDATE_IN_PROGRESS", "Bucket2 UPDATE_COMPLETE", "Bucket3 UPDATE_COMPLETE", "Bucket4 UPDATE_COMPLETE", "Bucket5 UPDATE_COMPLETE", "Bucket6 UPDATE_COMPLETE"}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Fatalf("Got %v but expected %v", got, want)
+		}
+
+		// Paging stops at the page with the event from before the clock skew, the oldest page is never read
+		if api.calls != 4 {
+			t.Fatalf("Got %d calls but expected 4", api.calls)
+		}
+
+		// Only new events are returned, paging stops at the first event already seen
+		api.events["bar"] = append(api.events["bar"], stackEvent("7", "UPDATE_COMPLETE", "fogmachine-1", start.Add(10*time.Second)))
+		api.calls = 0
+
+		if got = names(t, tracker, api); fmt.Sprint(got) != "[bar UPDATE_COMPLETE]" || api.calls != 1 {
+			t.Fatalf("Got %v after %d calls but expected only the new event after 1", got, api.calls)
+		}
+	})
+
+	t.Run("adopts the token of the operation", func(t *testing.T) {
+		events := []types.StackEvent{
+			stackEvent("1", "UPDATE_IN_PROGRESS", "earlier", start.Add(-time.Minute)),
+			resourceEvent("2", "Earlier", "UPDATE_FAILED", "earlier", start.Add(time.Second)),
+			stackEvent("3", "UPDATE_IN_PROGRESS", "ours", start.Add(2*time.Second)),
+			resourceEvent("4", "Bucket", "UPDATE_IN_PROGRESS", "ours", start.Add(3*time.Second)),
+			resourceEvent("5", "Other", "UPDATE_FAILED", "other", start.Add(4*time.Second)),
+			resourceEvent("6", "Bucket", "UPDATE_COMPLETE", "", start.Add(5*time.Second)),
+		}
+
+		api := &pagedEvents{pageSize: 100, events: map[string][]types.StackEvent{"bar": events}}
+		tracker := client.NewEventTracker("bar", "", start)
+
+		got := names(t, tracker, api)
+		want := []string{"Earlier UPDATE_FAILED", "bar UPDATE_IN_PROGRESS", "Bucket UPDATE_IN_PROGRESS", "Bucket UPDATE_COMPLETE"}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Fatalf("Got %v but expected %v", got, want)
+		}
+	})
+}
+
+func TestEventTrackerNestedStacks(t *testing.T) {
+	start := time.Date(2024, 1, 2, 3, 0, 0, 0, time.UTC)
+	childID := "arn:aws:cloudformation:us-west-2:123456789012:stack/bar-Network-1/2"
+
+	inChild := func(event types.StackEvent) types.StackEvent {
+		event.StackId = aws.String(childID)
+		event.StackName = aws.String("bar-Network-1")
+		if aws.ToString(event.ResourceType) == "AWS::CloudFormation::Stack" {
+			event.LogicalResourceId = aws.String("bar-Network-1")
+			event.PhysicalResourceId = aws.String(childID)
+		}
+		return event
+	}
+
+	network := func(id, status, reason string, at time.Time) types.StackEvent {
+		event := resourceEvent(id, "Network", status, "fogmachine-1", at)
+		event.ResourceType = aws.String("AWS::CloudFormation::Stack")
+		event.PhysicalResourceId = aws.String(childID)
+		if reason != "" {
+			event.ResourceStatusReason = aws.String(reason)
+		}
+		return event
+	}
+
+	subnetFailed := resourceEvent("c3", "Subnet", "UPDATE_FAILED", "", start.Add(4*time.Second))
+	subnetFailed.ResourceStatusReason = aws.String("The CIDR '10.0.0.0/8' is invalid.")
+
+	api := &pagedEvents{pageSize: 2, events: map[string][]types.StackEvent{
+		"bar": {
+			stackEvent("1", "UPDATE_IN_PROGRESS", "fogmachine-1", start.Add(time.Second)),
+			network("2", "UPDATE_IN_PROGRESS", "", start.Add(2*time.Second)),
+			network("3", "UPDATE_FAILED", "Embedded stack arn:aws:cloudformation:us-west-2:123456789012:stack/bar-Network-1/2 was not successfully updated.", start.Add(6*time.Second)),
+			stackEvent("4", "UPDATE_ROLLBACK_IN_PROGRESS", "fogmachine-1", start.Add(7*time.Second)),
+		},
+		childID: {
+			inChild(stackEvent("c1", "UPDATE_IN_PROGRESS", "", start.Add(3*time.Second))),
+			inChild(resourceEvent("c2", "Subnet", "UPDATE_IN_PROGRESS", "", start.Add(3*time.Second))),
+			inChild(subnetFailed),
+			inChild(stackEvent("c4", "UPDATE_ROLLBACK_IN_PROGRESS", "", start.Add(5*time.Second))),
+		},
+	}}
+
+	tracker := client.NewEventTracker("bar", "fogmachine-1", start)
+
+	// The nested stack is discovered on the first poll and followed from the next
+	if _, err := tracker.Poll(context.Background(), api); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tracker.Poll(context.Background(), api); err != nil {
+		t.Fatal(err)
+	}
+
+	paths := map[string]string{}
+	for _, event := range tracker.Events() {
+		paths[event.ResourceName] = event.StackPath
+	}
+
+	if paths["Subnet"] != "bar/Network" {
+		t.Fatalf("Got stack path %q but expected %q", paths["Subnet"], "bar/Network")
+	}
+	if paths["Network"] != "bar" {
+		t.Fatalf("Got stack path %q but expected %q", paths["Network"], "bar")
+	}
+
+	failures := client.RootCauses(tracker.Events())
+	if len(failures) != 1 {
+		t.Fatalf("Got %d failures but expected 1: %v", len(failures), failures)
+	}
+
+	if failures[0].StackPath != "bar/Network" || failures[0].LogicalID != "Subnet" || failures[0].Reason != "The CIDR '10.0.0.0/8' is invalid." {
+		t.Fatalf("Got %+v but expected the Subnet failure in bar/Network", failures[0])
+	}
+}
@@ -2,40 +2,49 @@ package destroy
 
 import (
 	"context"
+	"errors"
 
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
 	"github.com/massdriver-cloud/fogmachine/pkg/client"
-	"github.com/rs/zerolog/log"
-	"github.com/spf13/cobra"
 )
 
-func Destroy(cmd *cobra.Command, _ []string) {
-	ctx := context.Background()
-	packageName, err := cmd.Flags().GetString("package-name")
-	if err != nil {
-		log.Fatal().Err(err).Msg("")
-	}
+// Options configure Destroy
+type Options struct {
+	client.Config
+}
 
-	region, err := cmd.Flags().GetString("region")
-	if err != nil {
-		log.Fatal().Err(err).Msg("")
-	}
+// Result is how a destroy ended
+type Result struct {
+	StackName   string
+	OperationID string
+	// Status is the status the stack finished in, DELETE_COMPLETE once it's gone or when it didn't exist
+	Status types.StackStatus
+	// Failures are the root causes of a failed destroy
+	Failures []client.Failure
+}
 
-	timeout, err := cmd.Flags().GetInt("timeout")
+// Destroy deletes the stack and waits for it to be gone. Progress is sent to opts.Events.
+func Destroy(ctx context.Context, opts Options) (*Result, error) {
+	cf, err := client.New(ctx, opts.Config)
 	if err != nil {
-		log.Fatal().Err(err).Msg("")
+		return nil, err
 	}
 
-	pollInterval, err := cmd.Flags().GetInt("poll-interval")
-	if err != nil {
-		log.Fatal().Err(err).Msg("")
-	}
+	result := &Result{StackName: opts.StackName, OperationID: cf.OperationID()}
 
-	client, err := client.NewCloudformationClient(ctx, packageName, region, timeout, pollInterval)
-	if err != nil {
-		log.Fatal().Err(err).Msg("")
+	if err = cf.WaitUntilIdle(ctx); err != nil {
+		return result, err
 	}
 
-	if err = client.ExecuteDestroyStack(ctx); err != nil {
-		log.Fatal().Err(err).Msg("")
+	if err = cf.ExecuteDestroyStack(ctx); err != nil {
+		var statusErr *client.StackStatusError
+		if errors.As(err, &statusErr) {
+			result.Status, result.Failures = statusErr.Status, statusErr.Failures
+		}
+		return result, err
 	}
+
+	result.Status = types.StackStatusDeleteComplete
+
+	return result, nil
 }
@@ -0,0 +1,75 @@
+package destroy_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/massdriver-cloud/fogmachine/pkg/client"
+	"github.com/massdriver-cloud/fogmachine/pkg/destroy"
+	"github.com/massdriver-cloud/fogmachine/pkg/testing/fake"
+)
+
+const template = `
+Resources:
+  MainBucket:
+    Type: AWS::S3::Bucket
+  DevBucket:
+    Type: AWS::S3::Bucket
+`
+
+func options(cf *fake.CloudFormation) destroy.Options {
+	return destroy.Options{Config: client.Config{
+		StackName:      "bar",
+		Timeout:        5 * time.Second,
+		PollInterval:   time.Millisecond,
+		CloudFormation: cf,
+		Events:         client.FuncSink(func(client.Event) {}),
+	}}
+}
+
+func TestDestroy(t *testing.T) {
+	cf := fake.New()
+	if err := cf.AddStack("bar", types.StackStatusCreateComplete, template); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := destroy.Destroy(context.Background(), options(cf))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Status != types.StackStatusDeleteComplete {
+		t.Fatalf("Got %s but expected %s", result.Status, types.StackStatusDeleteComplete)
+	}
+
+	if _, ok := cf.Stack("bar"); ok {
+		t.Fatalf("Got a stack but expected it to be deleted")
+	}
+
+	// Destroying a stack that doesn't exist does nothing
+	if _, err = destroy.Destroy(context.Background(), options(cf)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDestroyFailed(t *testing.T) {
+	cf := fake.New()
+	if err := cf.AddStack("bar", types.StackStatusCreateComplete, template); err != nil {
+		t.Fatal(err)
+	}
+	cf.FailResource("DevBucket", "The bucket you tried to delete is not empty")
+
+	result, err := destroy.Destroy(context.Background(), options(cf))
+
+	var statusErr *client.StackStatusError
+	if !errors.As(err, &statusErr) {
+		t.Fatalf("Got %v but expected a StackStatusError", err)
+	}
+
+	if result.Status != types.StackStatusDeleteFailed || len(result.Failures) != 1 || result.Failures[0].LogicalID != "DevBucket" {
+		t.Fatalf("Got %s with %+v but expected DELETE_FAILED caused by DevBucket", result.Status, result.Failures)
+	}
+}
@@ -1,15 +1,22 @@
 package eventcache
 
 import (
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
 )
 
 type Event struct {
+	StackID            string
+	StackPath          string
 	ResourceName       string
+	ResourceType       string
 	ResourceStatus     string
 	ProviderResourceID string
 	Message            string
 	Type               string
+	Timestamp          time.Time
 }
 
 type EventCache struct {
@@ -33,9 +40,14 @@ func (eventCache *EventCache) AddEvent(eventID string, event Event) {
 
 func (eventCache EventCache) EventFromStack(event types.StackEvent, eventType string) Event {
 	return Event{
-		ResourceName:       *event.LogicalResourceId,
-		ProviderResourceID: *event.PhysicalResourceId,
+		StackID:            aws.ToString(event.StackId),
+		StackPath:          aws.ToString(event.StackName),
+		ResourceName:       aws.ToString(event.LogicalResourceId),
+		ResourceType:       aws.ToString(event.ResourceType),
+		ProviderResourceID: aws.ToString(event.PhysicalResourceId),
 		ResourceStatus:     string(event.ResourceStatus),
+		Message:            aws.ToString(event.ResourceStatusReason),
 		Type:               eventType,
+		Timestamp:          aws.ToTime(event.Timestamp),
 	}
 }
@@ -0,0 +1,103 @@
+package exitcode
+
+import (
+	"errors"
+	"os"
+
+	"github.com/massdriver-cloud/fogmachine/pkg/client"
+	"github.com/massdriver-cloud/fogmachine/pkg/lock"
+	"github.com/rs/zerolog/log"
+)
+
+// Process exit codes, so CI can tell why a run ended without parsing logs
+const (
+	Success    = 0
+	Error      = 1
+	NoChanges  = 2
+	RolledBack = 3
+	Failed     = 4
+	Timeout    = 5
+	// Invalid is a template, parameter or capability the API rejected, or a changeset CloudFormation couldn't create
+	Invalid      = 6
+	NotFound     = 7
+	AccessDenied = 8
+	Throttled    = 9
+	// Busy is another operation running on the stack, or another run holding the lock file
+	Busy = 10
+)
+
+// FromError returns the exit code for the error an operation ended with
+func FromError(err error) int {
+	if err == nil {
+		return Success
+	}
+
+	if errors.Is(err, client.ErrNoChanges) {
+		return NoChanges
+	}
+
+	if errors.Is(err, client.ErrTimeout) {
+		return Timeout
+	}
+
+	var statusErr *client.StackStatusError
+	if errors.As(err, &statusErr) {
+		if statusErr.Category() == client.StatusRolledBack {
+			return RolledBack
+		}
+		return Failed
+	}
+
+	return fromAPIError(err)
+}
+
+// fromAPIError returns the exit code for a classified CloudFormation API error
+func fromAPIError(err error) int {
+	var (
+		validation   *client.ValidationError
+		capabilities *client.InsufficientCapabilitiesError
+		changeSet    *client.ChangeSetFailedError
+		notUpdatable *client.NotUpdatableError
+		notFound     *client.NotFoundError
+		accessDenied *client.AccessDeniedError
+		throttling   *client.ThrottlingError
+		busy         *client.StackBusyError
+		locked       *lock.LockedError
+	)
+
+	switch {
+	case errors.As(err, &validation), errors.As(err, &capabilities), errors.As(err, &changeSet):
+		return Invalid
+	case errors.As(err, &notUpdatable):
+		return Failed
+	case errors.As(err, &notFound):
+		return NotFound
+	case errors.As(err, &accessDenied):
+		return AccessDenied
+	case errors.As(err, &throttling):
+		return Throttled
+	case errors.As(err, &busy), errors.As(err, &locked):
+		return Busy
+	}
+
+	return Error
+}
+
+// Exit ends the process with the exit code for err, logging it unless it only signals that there was nothing to do.
+// It returns without exiting when err is nil.
+func Exit(err error) {
+	code := FromError(err)
+	if code == Success {
+		return
+	}
+
+	if code != NoChanges {
+		event := log.Error().Err(err)
+		if hint := client.Hint(err); hint != "" {
+			event = event.Str("hint", hint)
+		}
+		event.Msg("")
+	}
+
+	os.Exit(code)
+}
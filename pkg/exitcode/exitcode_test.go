@@ -0,0 +1,44 @@
+package exitcode_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/massdriver-cloud/fogmachine/pkg/client"
+	"github.com/massdriver-cloud/fogmachine/pkg/exitcode"
+	"github.com/massdriver-cloud/fogmachine/pkg/lock"
+)
+
+func TestFromError(t *testing.T) {
+	tests := map[string]struct {
+		err  error
+		want int
+	}{
+		"success":       {err: nil, want: exitcode.Success},
+		"no changes":    {err: client.ErrNoChanges, want: exitcode.NoChanges},
+		"timeout":       {err: fmt.Errorf("waiting: %w", client.ErrTimeout), want: exitcode.Timeout},
+		"rolled back":   {err: &client.StackStatusError{Status: types.StackStatusRollbackComplete}, want: exitcode.RolledBack},
+		"failed":        {err: &client.StackStatusError{Status: types.StackStatusUpdateRollbackFailed}, want: exitcode.Failed},
+		"validation":    {err: &client.ValidationError{Err: errors.New("bad template")}, want: exitcode.Invalid},
+		"capabilities":  {err: &client.InsufficientCapabilitiesError{Err: errors.New("needs iam")}, want: exitcode.Invalid},
+		"changeset":     {err: &client.ChangeSetFailedError{ChangesetID: "arn:foo", Reason: "Parameter 'Size' must be a number"}, want: exitcode.Invalid},
+		"not updatable": {err: &client.NotUpdatableError{Status: types.StackStatusRollbackComplete, Err: errors.New("busy")}, want: exitcode.Failed},
+		"not found":     {err: fmt.Errorf("describing: %w", &client.NotFoundError{Err: errors.New("gone")}), want: exitcode.NotFound},
+		"access denied": {err: &client.AccessDeniedError{Err: errors.New("denied")}, want: exitcode.AccessDenied},
+		"throttled":     {err: &client.ThrottlingError{Err: errors.New("slow down")}, want: exitcode.Throttled},
+		"busy":          {err: &client.StackBusyError{Status: types.StackStatusUpdateInProgress}, want: exitcode.Busy},
+		"busy timeout":  {err: &client.StackBusyError{Status: types.StackStatusUpdateInProgress, Err: client.ErrTimeout}, want: exitcode.Timeout},
+		"locked":        {err: &lock.LockedError{Path: "fogmachine.lock"}, want: exitcode.Busy},
+		"other":         {err: errors.New("boom"), want: exitcode.Error},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := exitcode.FromError(tc.err); got != tc.want {
+				t.Fatalf("Got %d but expected %d", got, tc.want)
+			}
+		})
+	}
+}
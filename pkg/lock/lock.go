@@ -0,0 +1,104 @@
+// Package lock is an advisory lock file so runs on the same host don't change the same stack at once
+package lock
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// errReleased is returned when the holder released the lock, removing the file, while it was being acquired
+var errReleased = errors.New("lock released")
+
+// Holder is what a lock file records about the run holding it
+type Holder struct {
+	Stack    string    `json:"stack"`
+	PID      int       `json:"pid"`
+	Hostname string    `json:"hostname"`
+	Acquired time.Time `json:"acquired"`
+}
+
+// LockedError is returned when another run holds the lock
+type LockedError struct {
+	Path   string
+	Holder Holder
+}
+
+func (e *LockedError) Error() string {
+	if e.Holder.PID == 0 {
+		return fmt.Sprintf("lock %s is held by another run", e.Path)
+	}
+	return fmt.Sprintf("lock %s is held by pid %d on %s for stack %s since %s",
+		e.Path, e.Holder.PID, e.Holder.Hostname, e.Holder.Stack, e.Holder.Acquired.Format(time.RFC3339))
+}
+
+// Hint suggests how to fix the error
+func (e *LockedError) Hint() string {
+	return "another fogmachine run on this host holds the lock, use --on-busy wait to wait for it"
+}
+
+// Lock is a held lock file. On unix the lock is an flock on the file, so the kernel releases it when the holding
+// process exits and a file left behind by a run that's gone is simply locked again. Elsewhere holding the lock is
+// creating the file, one left behind by a run that's gone has to be removed by hand.
+type Lock struct {
+	path string
+	file *os.File
+}
+
+// Acquire locks the lock file for the stack. When another run holds it Acquire retries every poll until wait
+// has passed, then returns a LockedError.
+func Acquire(path, stack string, wait, poll time.Duration) (*Lock, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+
+	holder := Holder{Stack: stack, PID: os.Getpid(), Hostname: hostname, Acquired: time.Now().UTC()}
+	content, err := json.Marshal(holder)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(wait)
+
+	for {
+		acquired, err := tryLock(path, content)
+		if err == nil {
+			return acquired, nil
+		}
+
+		if errors.Is(err, errReleased) {
+			continue
+		}
+
+		var locked *LockedError
+		if !errors.As(err, &locked) {
+			return nil, err
+		}
+
+		if time.Now().After(deadline) {
+			return nil, locked
+		}
+
+		time.Sleep(poll)
+	}
+}
+
+// held reads the holder of a locked file, a holder that can't be read yet is left empty
+func held(path string) error {
+	locked := &LockedError{Path: path}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return errReleased
+		}
+		return err
+	}
+
+	_ = json.Unmarshal(content, &locked.Holder)
+
+	return locked
+}
@@ -0,0 +1,37 @@
+//go:build !unix
+
+package lock
+
+import (
+	"errors"
+	"os"
+)
+
+// Release closes and removes the lock file
+func (l *Lock) Release() error {
+	// The file can't be removed while it's open on every platform
+	err := l.file.Close()
+	if removeErr := os.Remove(l.path); err == nil {
+		err = removeErr
+	}
+	return err
+}
+
+// tryLock creates the lock file and records the holder in it, or returns a LockedError naming the run holding it
+func tryLock(path string, content []byte) (*Lock, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o644)
+	if errors.Is(err, os.ErrExist) {
+		return nil, held(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = file.Write(content); err != nil {
+		_ = file.Close()
+		_ = os.Remove(path)
+		return nil, err
+	}
+
+	return &Lock{path: path, file: file}, nil
+}
@@ -0,0 +1,106 @@
+package lock_test
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/massdriver-cloud/fogmachine/pkg/lock"
+)
+
+func TestAcquire(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fogmachine.lock")
+
+	held, err := lock.Acquire(path, "bar", 0, time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = lock.Acquire(path, "bar", 5*time.Millisecond, time.Millisecond)
+
+	var locked *lock.LockedError
+	if !errors.As(err, &locked) || locked.Holder.PID != os.Getpid() || locked.Holder.Stack != "bar" {
+		t.Fatalf("Got %v but expected the lock to be held by this process", err)
+	}
+
+	if err = held.Release(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A lock left by a process that's gone is taken over
+	writeStale(t, path)
+
+	if held, err = lock.Acquire(path, "bar", 0, time.Millisecond); err != nil {
+		t.Fatalf("Got %v but expected the stale lock to be taken over", err)
+	}
+
+	if err = held.Release(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAcquireStaleRace(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fogmachine.lock")
+
+	for i := 0; i < 50; i++ {
+		writeStale(t, path)
+
+		var wg sync.WaitGroup
+		start := make(chan struct{})
+		locks := make([]*lock.Lock, 2)
+		errs := make([]error, 2)
+
+		for j := range locks {
+			wg.Add(1)
+			go func(j int) {
+				defer wg.Done()
+				<-start
+				locks[j], errs[j] = lock.Acquire(path, "bar", 0, time.Millisecond)
+			}(j)
+		}
+
+		close(start)
+		wg.Wait()
+
+		held := 0
+		for _, err := range errs {
+			var locked *lock.LockedError
+			switch {
+			case err == nil:
+				held++
+			case !errors.As(err, &locked):
+				t.Fatalf("Got %v but expected a LockedError", err)
+			}
+		}
+
+		if held != 1 {
+			t.Fatalf("Got %d runs holding the lock but expected exactly 1", held)
+		}
+
+		for _, held := range locks {
+			if held != nil {
+				if err := held.Release(); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+	}
+}
+
+// writeStale leaves a lock file behind for a process that's gone
+func writeStale(t *testing.T, path string) {
+	t.Helper()
+
+	stale, err := json.Marshal(lock.Holder{Stack: "bar", PID: 1 << 30, Hostname: "gone"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = os.WriteFile(path, stale, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,62 @@
+//go:build unix
+
+package lock
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// Release removes the lock file and unlocks it
+func (l *Lock) Release() error {
+	// The file is removed while it's still locked so a run waiting on it never locks a file that's gone
+	err := os.Remove(l.path)
+	if closeErr := l.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// tryLock locks the lock file and records the holder in it, or returns a LockedError naming the run holding it
+func tryLock(path string, content []byte) (*Lock, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		_ = file.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, held(path)
+		}
+		return nil, err
+	}
+
+	// The holder may have released the lock between the open and the flock, then the file we locked is gone
+	opened, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	current, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) || (err == nil && !os.SameFile(opened, current)) {
+		_ = file.Close()
+		return nil, errReleased
+	}
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	if err = file.Truncate(0); err == nil {
+		_, err = file.WriteAt(content, 0)
+	}
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	return &Lock{path: path, file: file}, nil
+}
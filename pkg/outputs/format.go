@@ -0,0 +1,176 @@
+package outputs
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	FormatJSON   = "json"
+	FormatYAML   = "yaml"
+	FormatDotenv = "dotenv"
+	FormatGitHub = "github"
+)
+
+type Options struct {
+	Format              string
+	IncludeExportNames  bool
+	IncludeDescriptions bool
+}
+
+// Output is a single stack output as written when export names or descriptions are included
+type Output struct {
+	Value       string `json:"value" yaml:"value"`
+	ExportName  string `json:"exportName,omitempty" yaml:"exportName,omitempty"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+}
+
+// Validate checks the format is one outputs can be written in
+func (o Options) Validate() error {
+	switch o.Format {
+	case FormatJSON, FormatYAML, FormatDotenv, FormatGitHub, "":
+		return nil
+	default:
+		return fmt.Errorf("unknown format %q, expected one of [%s, %s, %s, %s]", o.Format, FormatJSON, FormatYAML, FormatDotenv, FormatGitHub)
+	}
+}
+
+var plainValue = regexp.MustCompile(`^[A-Za-z0-9_./:@,+=-]*$`)
+
+// WriteFile writes the outputs to path. GitHub outputs are appended since $GITHUB_OUTPUT is shared
+// by every step of a job, other formats replace the file. The file isn't touched when the format is unknown.
+func WriteFile(path string, outputs []types.Output, opts Options) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if opts.Format == FormatGitHub {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+
+	f, err := os.OpenFile(path, flags, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return Write(f, outputs, opts)
+}
+
+// Write writes the outputs to w in the requested format, sorted by output key
+func Write(w io.Writer, outputs []types.Output, opts Options) error {
+	sorted := make([]types.Output, len(outputs))
+	copy(sorted, outputs)
+	sort.Slice(sorted, func(i, j int) bool {
+		return aws.ToString(sorted[i].OutputKey) < aws.ToString(sorted[j].OutputKey)
+	})
+
+	switch opts.Format {
+	case FormatJSON, "":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(structured(sorted, opts))
+	case FormatYAML:
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(structured(sorted, opts)); err != nil {
+			return err
+		}
+		return encoder.Close()
+	case FormatDotenv:
+		return writeLines(w, sorted, opts, dotenvLine)
+	case FormatGitHub:
+		return writeLines(w, sorted, opts, githubLine)
+	default:
+		return opts.Validate()
+	}
+}
+
+// structured maps output keys to their value, or to an Output when extra fields are requested
+func structured(outputs []types.Output, opts Options) map[string]interface{} {
+	result := make(map[string]interface{}, len(outputs))
+
+	for _, output := range outputs {
+		key := aws.ToString(output.OutputKey)
+		if !opts.IncludeExportNames && !opts.IncludeDescriptions {
+			result[key] = aws.ToString(output.OutputValue)
+			continue
+		}
+
+		o := Output{Value: aws.ToString(output.OutputValue)}
+		if opts.IncludeExportNames {
+			o.ExportName = aws.ToString(output.ExportName)
+		}
+		if opts.IncludeDescriptions {
+			o.Description = aws.ToString(output.Description)
+		}
+		result[key] = o
+	}
+
+	return result
+}
+
+// writeLines writes one key value pair per output and, when requested, one each for its export
+// name and description suffixed with _EXPORT_NAME and _DESCRIPTION
+func writeLines(w io.Writer, outputs []types.Output, opts Options, line func(key, value string) (string, error)) error {
+	for _, output := range outputs {
+		key := aws.ToString(output.OutputKey)
+		pairs := [][2]string{{key, aws.ToString(output.OutputValue)}}
+
+		if opts.IncludeExportNames && output.ExportName != nil {
+			pairs = append(pairs, [2]string{key + "_EXPORT_NAME", *output.ExportName})
+		}
+		if opts.IncludeDescriptions && output.Description != nil {
+			pairs = append(pairs, [2]string{key + "_DESCRIPTION", *output.Description})
+		}
+
+		for _, pair := range pairs {
+			l, err := line(pair[0], pair[1])
+			if err != nil {
+				return err
+			}
+			if _, err = io.WriteString(w, l); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func dotenvLine(key, value string) (string, error) {
+	if plainValue.MatchString(value) {
+		return fmt.Sprintf("%s=%s\n", key, value), nil
+	}
+
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`, "\n", `\n`)
+	return fmt.Sprintf("%s=\"%s\"\n", key, replacer.Replace(value)), nil
+}
+
+// githubLine uses the heredoc syntax for multiline values, with a random delimiter so a value
+// can never end it early
+func githubLine(key, value string) (string, error) {
+	if !strings.ContainsAny(value, "\r\n") {
+		return fmt.Sprintf("%s=%s\n", key, value), nil
+	}
+
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	delimiter := "ghadelimiter_" + hex.EncodeToString(random)
+
+	return fmt.Sprintf("%s<<%s\n%s\n%s\n", key, delimiter, value, delimiter), nil
+}
@@ -0,0 +1,140 @@
+package outputs_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/massdriver-cloud/fogmachine/pkg/outputs"
+)
+
+func testOutputs() []types.Output {
+	return []types.Output{
+		{
+			OutputKey:   aws.String("MainBucketName"),
+			OutputValue: aws.String("md-test-cf-1234"),
+			Description: aws.String("Name of the main bucket"),
+			ExportName:  aws.String("md-test-main-bucket"),
+		},
+		{
+			OutputKey:   aws.String("Certificate"),
+			OutputValue: aws.String("-----BEGIN-----\nabc $HOME\n-----END-----"),
+		},
+	}
+}
+
+func TestWrite(t *testing.T) {
+	tests := map[string]struct {
+		opts outputs.Options
+		want string
+	}{
+		"json": {
+			opts: outputs.Options{Format: outputs.FormatJSON},
+			want: `{
+  "Certificate": "-----BEGIN-----\nabc $HOME\n-----END-----",
+  "MainBucketName": "md-test-cf-1234"
+}
+`,
+		},
+		"json with extras": {
+			opts: outputs.Options{Format: outputs.FormatJSON, IncludeExportNames: true, IncludeDescriptions: true},
+			want: `{
+  "Certificate": {
+    "value": "-----BEGIN-----\nabc $HOME\n-----END-----"
+  },
+  "MainBucketName": {
+    "value": "md-test-cf-1234",
+    "exportName": "md-test-main-bucket",
+    "description": "Name of the main bucket"
+  }
+}
+`,
+		},
+		"yaml": {
+			opts: outputs.Options{Format: outputs.FormatYAML, IncludeExportNames: true},
+			want: `Certificate:
+  value: |-
+    -----BEGIN-----
+    abc $HOME
+    -----END-----
+MainBucketName:
+  value: md-test-cf-1234
+  exportName: md-test-main-bucket
+`,
+		},
+		"dotenv": {
+			opts: outputs.Options{Format: outputs.FormatDotenv, IncludeDescriptions: true},
+			want: `Certificate="-----BEGIN-----\nabc \$HOME\n-----END-----"
+MainBucketName=md-test-cf-1234
+MainBucketName_DESCRIPTION="Name of the main bucket"
+`,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := outputs.Write(&buf, testOutputs(), tc.opts); err != nil {
+				t.Fatal(err)
+			}
+
+			if buf.String() != tc.want {
+				t.Fatalf("Got %s but expected %s", buf.String(), tc.want)
+			}
+		})
+	}
+}
+
+func TestWriteFileGitHub(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "github_output")
+	if err := os.WriteFile(path, []byte("existing=value\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := outputs.WriteFile(path, testOutputs(), outputs.Options{Format: outputs.FormatGitHub}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := regexp.MustCompile(`^existing=value
+Certificate<<(ghadelimiter_[0-9a-f]+)
+-----BEGIN-----
+abc \$HOME
+-----END-----
+(ghadelimiter_[0-9a-f]+)
+MainBucketName=md-test-cf-1234
+$`)
+
+	matches := want.FindStringSubmatch(string(got))
+	if matches == nil || matches[1] != matches[2] {
+		t.Fatalf("Got %s", got)
+	}
+}
+
+func TestWriteFileUnknownFormat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outputs.json")
+	if err := os.WriteFile(path, []byte("{}\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := outputs.WriteFile(path, testOutputs(), outputs.Options{Format: "xml"}); err == nil {
+		t.Fatal("expected an error for an unknown format")
+	}
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(got) != "{}\n" {
+		t.Fatalf("Got %s but expected the file to be left alone", got)
+	}
+}
@@ -0,0 +1,37 @@
+package outputs
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/massdriver-cloud/fogmachine/pkg/client"
+	"github.com/massdriver-cloud/fogmachine/pkg/redact"
+	"github.com/rs/zerolog/log"
+)
+
+// Save reads the stack outputs and writes them to path, or to stdout when path is empty
+func Save(ctx context.Context, cf *client.Client, path string, opts Options) error {
+	outputs, err := cf.Outputs(ctx)
+	if err != nil {
+		return err
+	}
+
+	return WriteTo(path, outputs, opts)
+}
+
+// WriteTo writes the outputs to path, or to stdout when path is empty
+func WriteTo(path string, outputs []types.Output, opts Options) error {
+	if path == "" {
+		return Write(redact.Writer(os.Stdout), outputs, opts)
+	}
+
+	if err := WriteFile(path, outputs, opts); err != nil {
+		return fmt.Errorf("unable to write outputs: %w", err)
+	}
+
+	log.Info().Str("phase", "Outputs").Str("file", path).Int("outputs", len(outputs)).Msg("Wrote stack outputs")
+
+	return nil
+}
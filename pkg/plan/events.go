@@ -0,0 +1,39 @@
+package plan
+
+import (
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/massdriver-cloud/fogmachine/pkg/client"
+)
+
+// emitChanges reports the plan as events instead of a report, for --output json
+func emitChanges(cf *client.Client, changes []types.Change, parameters []ParameterChange) {
+	for _, change := range parameters {
+		cf.Emit(client.Event{
+			Phase:       client.PhaseChangeset,
+			Type:        client.EventParameterChange,
+			ChangesetID: cf.ChangesetID(),
+			LogicalID:   change.Name,
+			Action:      change.Action,
+			Before:      change.Before,
+			After:       change.After,
+		})
+	}
+
+	for _, change := range changes {
+		if change.ResourceChange == nil {
+			continue
+		}
+
+		rc := fromResourceChange(change.ResourceChange)
+		cf.Emit(client.Event{
+			Phase:        client.PhaseChangeset,
+			Type:         client.EventResourceChange,
+			ChangesetID:  cf.ChangesetID(),
+			LogicalID:    rc.LogicalID,
+			PhysicalID:   rc.PhysicalID,
+			ResourceType: rc.ResourceType,
+			Action:       rc.Action,
+			Replacement:  rc.Replacement,
+		})
+	}
+}
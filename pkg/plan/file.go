@@ -0,0 +1,135 @@
+package plan
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/massdriver-cloud/fogmachine/pkg/template"
+)
+
+const fileVersion = 2
+
+// File records a changeset created by plan so a later apply can execute exactly that changeset
+type File struct {
+	Version       int    `json:"version"`
+	ChangesetID   string `json:"changesetId"`
+	StackName     string `json:"stackName"`
+	Region        string `json:"region"`
+	TemplateHash  string `json:"templateHash"`
+	ParameterHash string `json:"parameterHash"`
+	// ParameterKey keys the HMAC in ParameterHash. It's random for every plan so the hash of a NoEcho value
+	// can't be matched against other plans or a table of precomputed hashes.
+	ParameterKey string    `json:"parameterKey"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+func NewFile(changesetID, stackName, region string, tmpl *template.Output) (File, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return File{}, err
+	}
+
+	return File{
+		Version:       fileVersion,
+		ChangesetID:   changesetID,
+		StackName:     stackName,
+		Region:        region,
+		TemplateHash:  HashTemplate(tmpl.Template),
+		ParameterHash: HashParameters(key, tmpl.Parameters),
+		ParameterKey:  hex.EncodeToString(key),
+		CreatedAt:     time.Now().UTC(),
+	}, nil
+}
+
+func (f File) Write(path string) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append(data, '\n'), 0o600)
+}
+
+func ReadFile(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &File{}
+	if err = json.Unmarshal(data, f); err != nil {
+		return nil, fmt.Errorf("unable to parse plan file %s: %w", path, err)
+	}
+
+	if f.Version != fileVersion {
+		return nil, fmt.Errorf("plan file %s has version %d, expected %d", path, f.Version, fileVersion)
+	}
+
+	if f.ChangesetID == "" {
+		return nil, fmt.Errorf("plan file %s does not contain a changeset ID", path)
+	}
+
+	return f, nil
+}
+
+// Verify checks that the plan was made for the given stack and region. If a template is passed
+// its hashes must also match the ones recorded when the plan was made.
+func (f File) Verify(stackName, region string, tmpl *template.Output) error {
+	if f.StackName != stackName {
+		return fmt.Errorf("plan was created for stack %s, not %s", f.StackName, stackName)
+	}
+
+	if f.Region != region {
+		return fmt.Errorf("plan was created in region %s, not %s", f.Region, region)
+	}
+
+	if tmpl == nil {
+		return nil
+	}
+
+	if HashTemplate(tmpl.Template) != f.TemplateHash {
+		return errors.New("template has changed since the plan was created")
+	}
+
+	key, err := hex.DecodeString(f.ParameterKey)
+	if err != nil {
+		return fmt.Errorf("plan file has an invalid parameter key: %w", err)
+	}
+
+	if !hmac.Equal([]byte(HashParameters(key, tmpl.Parameters)), []byte(f.ParameterHash)) {
+		return errors.New("parameters have changed since the plan was created")
+	}
+
+	return nil
+}
+
+func HashTemplate(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// HashParameters is the HMAC-SHA256 of the parameters keyed with key, independently of their order
+func HashParameters(key []byte, parameters []types.Parameter) string {
+	sorted := make([]types.Parameter, len(parameters))
+	copy(sorted, parameters)
+	sort.Slice(sorted, func(i, j int) bool {
+		return aws.ToString(sorted[i].ParameterKey) < aws.ToString(sorted[j].ParameterKey)
+	})
+
+	hash := hmac.New(sha256.New, key)
+	for _, p := range sorted {
+		fmt.Fprintf(hash, "%q=%q;%t\n", aws.ToString(p.ParameterKey), aws.ToString(p.ParameterValue), aws.ToBool(p.UsePreviousValue))
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
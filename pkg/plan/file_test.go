@@ -0,0 +1,108 @@
+package plan_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/massdriver-cloud/fogmachine/pkg/plan"
+	"github.com/massdriver-cloud/fogmachine/pkg/template"
+)
+
+func TestPlanFileRoundTrip(t *testing.T) {
+	tmpl := &template.Output{
+		Template: []byte("Resources: {}"),
+		Parameters: []types.Parameter{
+			{ParameterKey: aws.String("B"), ParameterValue: aws.String("2")},
+			{ParameterKey: aws.String("A"), ParameterValue: aws.String("1")},
+		},
+	}
+
+	planned, err := plan.NewFile("arn:changeset", "stack", "us-west-2", tmpl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "plan.json")
+	if err = planned.Write(path); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := plan.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.ChangesetID != "arn:changeset" {
+		t.Fatalf("Got %s but expected arn:changeset", got.ChangesetID)
+	}
+
+	reordered := &template.Output{
+		Template:   tmpl.Template,
+		Parameters: []types.Parameter{tmpl.Parameters[1], tmpl.Parameters[0]},
+	}
+	if err = got.Verify("stack", "us-west-2", reordered); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = got.Verify("stack", "us-west-2", nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPlanFileVerifyMismatch(t *testing.T) {
+	tmpl := &template.Output{Template: []byte("Resources: {}")}
+	planned, err := plan.NewFile("arn:changeset", "stack", "us-west-2", tmpl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		stack  string
+		region string
+		tmpl   *template.Output
+	}{
+		"stack":    {stack: "other", region: "us-west-2"},
+		"region":   {stack: "stack", region: "us-east-1"},
+		"template": {stack: "stack", region: "us-west-2", tmpl: &template.Output{Template: []byte("Resources: {Foo: {}}")}},
+		"parameters": {stack: "stack", region: "us-west-2", tmpl: &template.Output{
+			Template:   tmpl.Template,
+			Parameters: []types.Parameter{{ParameterKey: aws.String("A"), ParameterValue: aws.String("1")}},
+		}},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if err := planned.Verify(tc.stack, tc.region, tc.tmpl); err == nil {
+				t.Fatal("expected verification to fail")
+			}
+		})
+	}
+}
+
+func TestPlanFileParameterHashIsKeyed(t *testing.T) {
+	tmpl := &template.Output{
+		Template:   []byte("Resources: {}"),
+		Parameters: []types.Parameter{{ParameterKey: aws.String("Password"), ParameterValue: aws.String("hunter22")}},
+	}
+
+	first, err := plan.NewFile("arn:changeset", "stack", "us-west-2", tmpl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := plan.NewFile("arn:changeset", "stack", "us-west-2", tmpl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The same parameters hash differently in every plan
+	if first.ParameterKey == second.ParameterKey || first.ParameterHash == second.ParameterHash {
+		t.Fatalf("Got %s and %s but expected each plan to have its own key and hash", first.ParameterHash, second.ParameterHash)
+	}
+
+	if err = second.Verify("stack", "us-west-2", tmpl); err != nil {
+		t.Fatal(err)
+	}
+}
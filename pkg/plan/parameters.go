@@ -0,0 +1,144 @@
+package plan
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/massdriver-cloud/fogmachine/pkg/template"
+	"github.com/rs/zerolog/log"
+)
+
+// Parameter actions, compared with the parameters of the existing stack
+const (
+	ParameterAdd       = "Add"
+	ParameterModify    = "Modify"
+	ParameterRemove    = "Remove"
+	ParameterUnchanged = "Unchanged"
+	ParameterPrevious  = "UsePrevious"
+	// ParameterUnknown is a NoEcho parameter, CloudFormation doesn't return its value to compare
+	ParameterUnknown = "Unknown"
+)
+
+// ParameterChange is how a parameter changes compared with the existing stack. NoEcho values are redacted.
+type ParameterChange struct {
+	Action string `json:"action"`
+	Name   string `json:"name"`
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+}
+
+// DiffParameters compares the parameters the changeset sends, or the template defaults it falls back on, with
+// the parameters of the existing stack
+func DiffParameters(existing []types.Parameter, tmpl *template.Output) []ParameterChange {
+	before := make(map[string]string, len(existing))
+	for _, parameter := range existing {
+		before[aws.ToString(parameter.ParameterKey)] = aws.ToString(parameter.ParameterValue)
+	}
+
+	sent := make(map[string]types.Parameter, len(tmpl.Parameters))
+	for _, parameter := range tmpl.Parameters {
+		sent[aws.ToString(parameter.ParameterKey)] = parameter
+	}
+
+	definitions := make([]*template.ParameterDefinition, 0, len(tmpl.Definitions))
+	for _, definition := range tmpl.Definitions {
+		definitions = append(definitions, definition)
+	}
+	sort.Slice(definitions, func(i, j int) bool {
+		return definitions[i].Node.Line < definitions[j].Node.Line
+	})
+
+	changes := []ParameterChange{}
+	for _, definition := range definitions {
+		old, existed := before[definition.Name]
+		change := ParameterChange{Name: definition.Name, Before: old}
+
+		parameter, ok := sent[definition.Name]
+		switch {
+		case ok && aws.ToBool(parameter.UsePreviousValue):
+			change.Action, change.After = ParameterPrevious, old
+		case ok:
+			change.After = aws.ToString(parameter.ParameterValue)
+		case definition.Default != nil:
+			change.After = *definition.Default
+		}
+
+		if change.Action == "" {
+			change.Action = parameterAction(existed, definition.NoEcho, old, change.After)
+		}
+
+		if definition.NoEcho {
+			change.Before, change.After = redactValue(change.Before), redactValue(change.After)
+		}
+
+		changes = append(changes, change)
+	}
+
+	removed := []ParameterChange{}
+	for name, old := range before {
+		if _, ok := tmpl.Definitions[name]; !ok {
+			removed = append(removed, ParameterChange{Action: ParameterRemove, Name: name, Before: old})
+		}
+	}
+	sort.Slice(removed, func(i, j int) bool {
+		return removed[i].Name < removed[j].Name
+	})
+
+	return append(changes, removed...)
+}
+
+func parameterAction(existed, noEcho bool, before, after string) string {
+	switch {
+	case !existed:
+		return ParameterAdd
+	case noEcho:
+		return ParameterUnknown
+	case before == after:
+		return ParameterUnchanged
+	default:
+		return ParameterModify
+	}
+}
+
+func redactValue(value string) string {
+	if value == "" {
+		return ""
+	}
+	return template.Redacted
+}
+
+// RenderParameters writes the parameter changes as a table
+func RenderParameters(w io.Writer, changes []ParameterChange) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PARAMETER\tACTION\tBEFORE\tAFTER")
+
+	for _, change := range changes {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", change.Name, change.Action, orDash(change.Before), orDash(change.After))
+	}
+
+	return tw.Flush()
+}
+
+// WriteParameterChanges prints the parameter changes ahead of the resource changes. The JSON format is a
+// single document of resource changes so the parameter changes are logged instead.
+func WriteParameterChanges(w io.Writer, changes []ParameterChange, format string) error {
+	if format != FormatJSON {
+		return RenderParameters(w, changes)
+	}
+
+	for _, change := range changes {
+		log.Info().
+			Str("phase", "Changeset").
+			Str("parameter", change.Name).
+			Str("action", change.Action).
+			Str("before", change.Before).
+			Str("after", change.After).
+			Msg("Parameter")
+	}
+
+	return nil
+}
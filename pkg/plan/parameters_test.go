@@ -0,0 +1,43 @@
+package plan_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/massdriver-cloud/fogmachine/pkg/plan"
+	"github.com/massdriver-cloud/fogmachine/pkg/template"
+)
+
+func TestDiffParameters(t *testing.T) {
+	tmpl, err := template.Read(template.Input{
+		TemplatePath:       "../template/testdata/s3.yaml",
+		Overrides:          []string{"BucketName=md-test-cf-1234", "DevBucketName=md-test-dev-cf-5678", "ConnectionsAuth=secret"},
+		ExistingParameters: []string{"BucketName", "DevBucketName", "ConnectionsAuth", "Retired"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	existing := []types.Parameter{
+		{ParameterKey: aws.String("BucketName"), ParameterValue: aws.String("md-test-cf-1234")},
+		{ParameterKey: aws.String("DevBucketName"), ParameterValue: aws.String("md-test-dev-cf-1234")},
+		{ParameterKey: aws.String("ConnectionsAuth"), ParameterValue: aws.String("****")},
+		{ParameterKey: aws.String("Retired"), ParameterValue: aws.String("old")},
+	}
+
+	got := plan.DiffParameters(existing, tmpl)
+
+	want := []plan.ParameterChange{
+		{Action: plan.ParameterUnchanged, Name: "BucketName", Before: "md-test-cf-1234", After: "md-test-cf-1234"},
+		{Action: plan.ParameterModify, Name: "DevBucketName", Before: "md-test-dev-cf-1234", After: "md-test-dev-cf-5678"},
+		{Action: plan.ParameterUnknown, Name: "ConnectionsAuth", Before: template.Redacted, After: template.Redacted},
+		{Action: plan.ParameterAdd, Name: "ConnectionsRegion", After: "us-west-2"},
+		{Action: plan.ParameterRemove, Name: "Retired", Before: "old"},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Got %v but expected %v", got, want)
+	}
+}
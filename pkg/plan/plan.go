@@ -0,0 +1,130 @@
+package plan
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/massdriver-cloud/fogmachine/pkg/client"
+	"github.com/massdriver-cloud/fogmachine/pkg/template"
+	"github.com/rs/zerolog/log"
+)
+
+// Options configure Plan
+type Options struct {
+	client.Config
+
+	// Template is the template and parameters to plan
+	Template template.Input
+	// Stack is passed to the changeset, tags from the parameter files are added underneath its tags
+	Stack client.StackOptions
+	// EffectiveParameters is where the merged parameters are printed, they aren't printed when it's nil
+	EffectiveParameters io.Writer
+	// EmitChanges sends the parameter and resource changes to Events too, otherwise they're only on the Result
+	EmitChanges bool
+	// PlanFile is where the changeset and the template and parameter hashes are written for a later
+	// apply --plan-file. Nothing is written when it's empty or the empty changeset was deleted.
+	PlanFile string
+}
+
+// Result is what the changeset would change
+type Result struct {
+	StackName   string
+	OperationID string
+	ChangesetID string
+	// NoChanges is set when the changeset has nothing to change
+	NoChanges  bool
+	Changes    []types.Change
+	Parameters []ParameterChange
+}
+
+// Plan creates a changeset for the template and returns what it would change without executing it. A changeset
+// without changes isn't an error.
+func Plan(ctx context.Context, opts Options) (*Result, error) {
+	cf, err := client.New(ctx, opts.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{StackName: opts.StackName, OperationID: cf.OperationID(), Changes: []types.Change{}}
+
+	existing, err := cf.StackParameters(ctx)
+	if err != nil {
+		return result, err
+	}
+
+	tmpl, err := ReadTemplate(opts.Template, existing, opts.EffectiveParameters)
+	if err != nil {
+		return result, err
+	}
+
+	cf.Redactor().AddValues(tmpl.Secrets...)
+
+	err = cf.CreateChangeset(ctx, tmpl.Template, tmpl.Parameters, opts.Stack.WithDefaultTags(tmpl.Tags))
+	result.ChangesetID = cf.ChangesetID()
+	result.NoChanges = errors.Is(err, client.ErrNoChanges)
+	if err != nil && !result.NoChanges {
+		return result, err
+	}
+
+	if !result.NoChanges {
+		if result.Changes, err = cf.Changes(ctx); err != nil {
+			return result, err
+		}
+	}
+
+	result.Parameters = DiffParameters(existing, tmpl)
+	if opts.EmitChanges {
+		emitChanges(cf, result.Changes, result.Parameters)
+	}
+
+	return result, writePlanFile(opts, result, tmpl)
+}
+
+// writePlanFile records the changeset for a later apply --plan-file when the options ask for it
+func writePlanFile(opts Options, result *Result, tmpl *template.Output) error {
+	if opts.PlanFile == "" {
+		return nil
+	}
+
+	if result.NoChanges && opts.DeleteEmptyChangeset {
+		log.Warn().Str("phase", "Changeset").Msg("Not writing the plan file, the empty changeset was deleted")
+		return nil
+	}
+
+	file, err := NewFile(result.ChangesetID, opts.StackName, opts.Region, tmpl)
+	if err != nil {
+		return err
+	}
+
+	if err = file.Write(opts.PlanFile); err != nil {
+		return err
+	}
+
+	log.Info().Str("phase", "Changeset").Str("planFile", opts.PlanFile).Msg("Wrote plan file")
+
+	return nil
+}
+
+// ReadTemplate reads the template and parameters. Parameters that aren't set keep their value on the existing
+// stack as the input allows, and the effective parameters are printed to effective unless it's nil.
+func ReadTemplate(input template.Input, existing []types.Parameter, effective io.Writer) (*template.Output, error) {
+	input.ExistingParameters = make([]string, 0, len(existing))
+	for _, parameter := range existing {
+		input.ExistingParameters = append(input.ExistingParameters, aws.ToString(parameter.ParameterKey))
+	}
+
+	tmpl, err := template.Read(input)
+	if err != nil {
+		return nil, err
+	}
+	tmpl.LogMappings()
+
+	if effective != nil {
+		err = tmpl.WriteEffectiveParameters(effective)
+	}
+
+	return tmpl, err
+}
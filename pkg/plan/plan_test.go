@@ -0,0 +1,65 @@
+package plan_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/massdriver-cloud/fogmachine/pkg/apply"
+	"github.com/massdriver-cloud/fogmachine/pkg/client"
+	"github.com/massdriver-cloud/fogmachine/pkg/plan"
+	"github.com/massdriver-cloud/fogmachine/pkg/template"
+	"github.com/massdriver-cloud/fogmachine/pkg/testing/fake"
+)
+
+func TestPlan(t *testing.T) {
+	cf := fake.New()
+	planFile := filepath.Join(t.TempDir(), "plan.json")
+
+	cfg := client.Config{
+		StackName:      "bar",
+		Region:         "us-west-2",
+		Timeout:        5 * time.Second,
+		PollInterval:   time.Millisecond,
+		CloudFormation: cf,
+		Events:         client.FuncSink(func(client.Event) {}),
+	}
+	input := template.Input{
+		TemplatePath:   "../template/testdata/s3.yaml",
+		ParameterPaths: []string{"../template/testdata/s3-values.json"},
+	}
+
+	result, err := plan.Plan(context.Background(), plan.Options{Config: cfg, Template: input, PlanFile: planFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.NoChanges || len(result.Changes) != 2 || result.ChangesetID == "" {
+		t.Fatalf("Got %d changes in changeset %q but expected 2", len(result.Changes), result.ChangesetID)
+	}
+
+	if cf.Calls("ExecuteChangeSet") != 0 {
+		t.Fatalf("Got %d executions but expected the plan not to execute the changeset", cf.Calls("ExecuteChangeSet"))
+	}
+
+	planned, err := plan.ReadFile(planFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if planned.ChangesetID != result.ChangesetID {
+		t.Fatalf("Got %s but expected %s", planned.ChangesetID, result.ChangesetID)
+	}
+
+	// Applying the plan executes the changeset it recorded
+	applied, err := apply.Apply(context.Background(), apply.Options{Config: cfg, Template: input, PlanFile: planFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if applied.Status != types.StackStatusCreateComplete || applied.ChangesetID != result.ChangesetID {
+		t.Fatalf("Got %s for changeset %s but expected %s for %s", applied.Status, applied.ChangesetID, types.StackStatusCreateComplete, result.ChangesetID)
+	}
+}
@@ -0,0 +1,133 @@
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+)
+
+const (
+	FormatTable = "table"
+	FormatJSON  = "json"
+)
+
+// ResourceChange is the rendered form of a single CloudFormation change
+type ResourceChange struct {
+	Action          string   `json:"action"`
+	LogicalID       string   `json:"logicalId"`
+	PhysicalID      string   `json:"physicalId,omitempty"`
+	ResourceType    string   `json:"resourceType"`
+	Replacement     string   `json:"replacement,omitempty"`
+	Scope           []string `json:"scope"`
+	CausingEntities []string `json:"causingEntities"`
+}
+
+// Render writes the changes to w in the requested format
+func Render(w io.Writer, changes []types.Change, format string) error {
+	rendered := ResourceChanges(changes)
+
+	switch format {
+	case FormatJSON:
+		return renderJSON(w, rendered)
+	case FormatTable, "":
+		return renderTable(w, rendered)
+	default:
+		return ValidateFormat(format)
+	}
+}
+
+// ValidateFormat checks the format is one the changes can be rendered in
+func ValidateFormat(format string) error {
+	switch format {
+	case FormatTable, FormatJSON, "":
+		return nil
+	default:
+		return fmt.Errorf("unknown format %q, expected one of [%s, %s]", format, FormatTable, FormatJSON)
+	}
+}
+
+// ResourceChanges returns the rendered form of the resource changes
+func ResourceChanges(changes []types.Change) []ResourceChange {
+	rendered := make([]ResourceChange, 0, len(changes))
+	for _, change := range changes {
+		if change.ResourceChange == nil {
+			continue
+		}
+		rendered = append(rendered, fromResourceChange(change.ResourceChange))
+	}
+	return rendered
+}
+
+func fromResourceChange(rc *types.ResourceChange) ResourceChange {
+	change := ResourceChange{
+		Action:          string(rc.Action),
+		LogicalID:       aws.ToString(rc.LogicalResourceId),
+		PhysicalID:      aws.ToString(rc.PhysicalResourceId),
+		ResourceType:    aws.ToString(rc.ResourceType),
+		Replacement:     string(rc.Replacement),
+		Scope:           []string{},
+		CausingEntities: []string{},
+	}
+
+	for _, scope := range rc.Scope {
+		change.Scope = append(change.Scope, string(scope))
+	}
+
+	seen := make(map[string]bool)
+	for _, detail := range rc.Details {
+		entity := string(detail.ChangeSource)
+		if detail.CausingEntity != nil {
+			entity = *detail.CausingEntity
+		}
+
+		if entity == "" || seen[entity] {
+			continue
+		}
+
+		seen[entity] = true
+		change.CausingEntities = append(change.CausingEntities, entity)
+	}
+
+	return change
+}
+
+func renderJSON(w io.Writer, changes []ResourceChange) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(changes)
+}
+
+func renderTable(w io.Writer, changes []ResourceChange) error {
+	if len(changes) == 0 {
+		_, err := fmt.Fprintln(w, "No changes")
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ACTION\tLOGICAL ID\tTYPE\tREPLACEMENT\tSCOPE\tCAUSED BY")
+
+	for _, change := range changes {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			change.Action,
+			change.LogicalID,
+			change.ResourceType,
+			orDash(change.Replacement),
+			orDash(strings.Join(change.Scope, ", ")),
+			orDash(strings.Join(change.CausingEntities, ", ")),
+		)
+	}
+
+	return tw.Flush()
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
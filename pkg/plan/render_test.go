@@ -0,0 +1,123 @@
+package plan_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/massdriver-cloud/fogmachine/pkg/plan"
+)
+
+func testChanges() []types.Change {
+	return []types.Change{
+		{
+			Type: types.ChangeTypeResource,
+			ResourceChange: &types.ResourceChange{
+				Action:             types.ChangeActionModify,
+				LogicalResourceId:  aws.String("MainBucket"),
+				PhysicalResourceId: aws.String("md-test-cf-1234"),
+				ResourceType:       aws.String("AWS::S3::Bucket"),
+				Replacement:        types.ReplacementTrue,
+				Scope:              []types.ResourceAttribute{types.ResourceAttributeProperties},
+				Details: []types.ResourceChangeDetail{
+					{ChangeSource: types.ChangeSourceParameterReference, CausingEntity: aws.String("BucketName")},
+					{ChangeSource: types.ChangeSourceDirectModification},
+				},
+			},
+		},
+		{
+			Type: types.ChangeTypeResource,
+			ResourceChange: &types.ResourceChange{
+				Action:            types.ChangeActionAdd,
+				LogicalResourceId: aws.String("DevBucket"),
+				ResourceType:      aws.String("AWS::S3::Bucket"),
+			},
+		},
+	}
+}
+
+func TestRenderJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := plan.Render(&buf, testChanges(), plan.FormatJSON); err != nil {
+		t.Fatal(err)
+	}
+
+	got := []plan.ResourceChange{}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []plan.ResourceChange{
+		{
+			Action:          "Modify",
+			LogicalID:       "MainBucket",
+			PhysicalID:      "md-test-cf-1234",
+			ResourceType:    "AWS::S3::Bucket",
+			Replacement:     "True",
+			Scope:           []string{"Properties"},
+			CausingEntities: []string{"BucketName", "DirectModification"},
+		},
+		{
+			Action:          "Add",
+			LogicalID:       "DevBucket",
+			ResourceType:    "AWS::S3::Bucket",
+			Scope:           []string{},
+			CausingEntities: []string{},
+		},
+	}
+
+	if !reflect.DeepEqual(want, got) {
+		t.Fatalf("Got %v but expected %v", got, want)
+	}
+}
+
+func TestRenderTable(t *testing.T) {
+	var buf bytes.Buffer
+	if err := plan.Render(&buf, testChanges(), plan.FormatTable); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Got %d lines but expected 3: %s", len(lines), buf.String())
+	}
+
+	if !strings.HasPrefix(lines[0], "ACTION") {
+		t.Fatalf("Got header %s", lines[0])
+	}
+
+	for _, want := range []string{"Modify", "MainBucket", "True", "BucketName, DirectModification"} {
+		if !strings.Contains(lines[1], want) {
+			t.Fatalf("Got %s but expected it to contain %s", lines[1], want)
+		}
+	}
+}
+
+func TestRenderNoChanges(t *testing.T) {
+	var buf bytes.Buffer
+	if err := plan.Render(&buf, nil, plan.FormatTable); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := strings.TrimSpace(buf.String()); got != "No changes" {
+		t.Fatalf("Got %s but expected No changes", got)
+	}
+}
+
+func TestRenderUnknownFormat(t *testing.T) {
+	if err := plan.Render(&bytes.Buffer{}, nil, "xml"); err == nil {
+		t.Fatal("expected an error for an unknown format")
+	}
+
+	if err := plan.ValidateFormat("xml"); err == nil {
+		t.Fatal("expected ValidateFormat to reject an unknown format")
+	}
+
+	if err := plan.ValidateFormat(plan.FormatJSON); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,143 @@
+package redact
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Mask replaces every redacted value
+const Mask = "****"
+
+// minLength is the shortest value that's redacted, shorter values would mask unrelated text
+const minLength = 3
+
+// builtinPatterns match secret-looking text that is redacted without being registered
+var builtinPatterns = []*regexp.Regexp{
+	// Dynamic references resolve to secrets when CloudFormation reads them
+	regexp.MustCompile(`\{\{resolve:(?:secretsmanager|ssm-secure):[^}]*\}\}`),
+	// AWS access key IDs
+	regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`),
+}
+
+// Redactor replaces secret values and text matching secret patterns
+type Redactor struct {
+	mu       sync.RWMutex
+	values   []string
+	patterns []*regexp.Regexp
+}
+
+// New returns a redactor with the built in patterns
+func New() *Redactor {
+	return &Redactor{patterns: append([]*regexp.Regexp{}, builtinPatterns...)}
+}
+
+var global = New()
+
+// Default is the redactor the logger, reports and event sinks share
+func Default() *Redactor {
+	return global
+}
+
+// AddValues registers secret values, such as the values of NoEcho parameters
+func (r *Redactor) AddValues(values ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, value := range values {
+		if len(value) < minLength {
+			continue
+		}
+
+		r.values = append(r.values, value)
+
+		// Values are also redacted once they've been escaped into JSON
+		if encoded, err := json.Marshal(value); err == nil {
+			if escaped := string(encoded[1 : len(encoded)-1]); escaped != value {
+				r.values = append(r.values, escaped)
+			}
+		}
+	}
+
+	// Longer values first so a value containing another is replaced whole
+	sort.SliceStable(r.values, func(i, j int) bool {
+		return len(r.values[i]) > len(r.values[j])
+	})
+}
+
+// AddPatterns registers regular expressions for text that should be redacted
+func (r *Redactor) AddPatterns(patterns ...string) error {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid redaction pattern %q: %w", pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.patterns = append(r.patterns, compiled...)
+
+	return nil
+}
+
+// String returns s with every secret replaced by Mask
+func (r *Redactor) String(s string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, value := range r.values {
+		s = strings.ReplaceAll(s, value, Mask)
+	}
+
+	for _, pattern := range r.patterns {
+		s = pattern.ReplaceAllLiteralString(s, Mask)
+	}
+
+	return s
+}
+
+// Writer redacts everything written to w. Each write is redacted on its own, which suits writers that
+// receive a whole line or document at a time like the logger.
+func (r *Redactor) Writer(w io.Writer) io.Writer {
+	return &writer{redactor: r, out: w}
+}
+
+type writer struct {
+	redactor *Redactor
+	out      io.Writer
+}
+
+// Write reports the length of p as written so callers aren't confused by the redacted length
+func (w *writer) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(w.out, w.redactor.String(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// AddValues registers secret values with the default redactor
+func AddValues(values ...string) {
+	global.AddValues(values...)
+}
+
+// AddPatterns registers patterns with the default redactor
+func AddPatterns(patterns ...string) error {
+	return global.AddPatterns(patterns...)
+}
+
+// String redacts s with the default redactor
+func String(s string) string {
+	return global.String(s)
+}
+
+// Writer wraps w with the default redactor
+func Writer(w io.Writer) io.Writer {
+	return global.Writer(w)
+}
@@ -0,0 +1,137 @@
+package stackconfig
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/massdriver-cloud/fogmachine/pkg/client"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the stack configuration file, in YAML or JSON
+type Config struct {
+	Capabilities        []string          `yaml:"capabilities"`
+	Tags                map[string]string `yaml:"tags"`
+	RoleARN             string            `yaml:"roleArn"`
+	NotificationARNs    []string          `yaml:"notificationArns"`
+	Rollback            *Rollback         `yaml:"rollback"`
+	IncludeNestedStacks bool              `yaml:"includeNestedStacks"`
+}
+
+type Rollback struct {
+	MonitoringTimeInMinutes int32             `yaml:"monitoringTimeInMinutes"`
+	Triggers                []RollbackTrigger `yaml:"triggers"`
+}
+
+type RollbackTrigger struct {
+	Arn  string `yaml:"arn"`
+	Type string `yaml:"type"`
+}
+
+// Read parses a stack configuration file. Unknown keys are an error so typos don't silently drop settings.
+func Read(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &Config{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	if err = decoder.Decode(config); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("unable to parse stack config %s: %w", path, err)
+	}
+
+	return config, nil
+}
+
+// Merge layers other, usually the values of the command line flags, over c. Lists are added to, tags and the role
+// replace the ones in c, and rollback triggers are added with other's monitoring time replacing c's when it's set.
+func (c *Config) Merge(other Config) {
+	c.Capabilities = append(c.Capabilities, other.Capabilities...)
+
+	for key, value := range other.Tags {
+		if c.Tags == nil {
+			c.Tags = make(map[string]string)
+		}
+		c.Tags[key] = value
+	}
+
+	if other.RoleARN != "" {
+		c.RoleARN = other.RoleARN
+	}
+
+	c.NotificationARNs = append(c.NotificationARNs, other.NotificationARNs...)
+
+	if other.Rollback != nil {
+		if c.Rollback == nil {
+			c.Rollback = &Rollback{}
+		}
+		if other.Rollback.MonitoringTimeInMinutes != 0 {
+			c.Rollback.MonitoringTimeInMinutes = other.Rollback.MonitoringTimeInMinutes
+		}
+		c.Rollback.Triggers = append(c.Rollback.Triggers, other.Rollback.Triggers...)
+	}
+
+	c.IncludeNestedStacks = c.IncludeNestedStacks || other.IncludeNestedStacks
+}
+
+// ParseTags parses tags in the form Key=Value
+func ParseTags(tags []string) (map[string]string, error) {
+	parsed := make(map[string]string, len(tags))
+	for _, tag := range tags {
+		key, value, ok := strings.Cut(tag, "=")
+		if !ok {
+			return nil, fmt.Errorf("tag %q must be in the form Key=Value", tag)
+		}
+		parsed[key] = value
+	}
+
+	return parsed, nil
+}
+
+// StackOptions converts the configuration to the options the client passes to CloudFormation
+func (c *Config) StackOptions() client.StackOptions {
+	options := client.StackOptions{
+		Tags:                c.Tags,
+		RoleARN:             c.RoleARN,
+		NotificationARNs:    c.NotificationARNs,
+		IncludeNestedStacks: c.IncludeNestedStacks,
+	}
+
+	seen := make(map[string]bool)
+	for _, capability := range c.Capabilities {
+		capability = strings.ToUpper(strings.TrimSpace(capability))
+		if capability == "" || seen[capability] {
+			continue
+		}
+		seen[capability] = true
+		options.Capabilities = append(options.Capabilities, types.Capability(capability))
+	}
+
+	if c.Rollback != nil {
+		options.RollbackConfiguration = &types.RollbackConfiguration{
+			MonitoringTimeInMinutes: aws.Int32(c.Rollback.MonitoringTimeInMinutes),
+		}
+
+		for _, trigger := range c.Rollback.Triggers {
+			triggerType := trigger.Type
+			if triggerType == "" {
+				triggerType = "AWS::CloudWatch::Alarm"
+			}
+			options.RollbackConfiguration.RollbackTriggers = append(options.RollbackConfiguration.RollbackTriggers, types.RollbackTrigger{
+				Arn:  aws.String(trigger.Arn),
+				Type: aws.String(triggerType),
+			})
+		}
+	}
+
+	return options
+}
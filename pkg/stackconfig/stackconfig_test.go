@@ -0,0 +1,92 @@
+package stackconfig_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/massdriver-cloud/fogmachine/pkg/stackconfig"
+)
+
+func TestMerge(t *testing.T) {
+	config, err := stackconfig.Read("testdata/stack.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config.Merge(stackconfig.Config{
+		Capabilities:        []string{"capability_auto_expand", "CAPABILITY_IAM"},
+		Tags:                map[string]string{"env": "production"},
+		IncludeNestedStacks: true,
+	})
+
+	got := config.StackOptions()
+	if err = got.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	wantCapabilities := []types.Capability{types.CapabilityCapabilityIam, types.CapabilityCapabilityAutoExpand}
+	if !reflect.DeepEqual(got.Capabilities, wantCapabilities) {
+		t.Fatalf("Got %v but expected %v", got.Capabilities, wantCapabilities)
+	}
+
+	wantTags := map[string]string{"team": "platform", "env": "production"}
+	if !reflect.DeepEqual(got.Tags, wantTags) {
+		t.Fatalf("Got %v but expected %v", got.Tags, wantTags)
+	}
+
+	if got.RoleARN != "arn:aws:iam::123456789012:role/cfn-deploy" || !got.IncludeNestedStacks {
+		t.Fatalf("Got %+v", got)
+	}
+
+	if got.RollbackConfiguration == nil || aws.ToInt32(got.RollbackConfiguration.MonitoringTimeInMinutes) != 5 {
+		t.Fatalf("Got rollback configuration %+v", got.RollbackConfiguration)
+	}
+
+	if trigger := got.RollbackConfiguration.RollbackTriggers[0]; aws.ToString(trigger.Type) != "AWS::CloudWatch::Alarm" {
+		t.Fatalf("Got trigger type %s", aws.ToString(trigger.Type))
+	}
+}
+
+func TestMergeRollback(t *testing.T) {
+	config, err := stackconfig.Read("testdata/stack.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config.Merge(stackconfig.Config{Rollback: &stackconfig.Rollback{
+		Triggers: []stackconfig.RollbackTrigger{{Arn: "arn:aws:cloudwatch:us-west-2:123456789012:alarm:latency"}},
+	}})
+
+	if config.Rollback.MonitoringTimeInMinutes != 5 || len(config.Rollback.Triggers) != 2 {
+		t.Fatalf("Got %+v but expected the file's monitoring time with both triggers", config.Rollback)
+	}
+}
+
+func TestInvalid(t *testing.T) {
+	if _, err := stackconfig.ParseTags([]string{"missing-value"}); err == nil {
+		t.Fatal("expected an error for a tag without a value")
+	}
+
+	config := stackconfig.Config{}
+	config.Merge(stackconfig.Config{NotificationARNs: []string{"not-an-arn"}})
+
+	options := config.StackOptions()
+	if err := options.Validate(); err == nil {
+		t.Fatal("expected an error for an invalid notification ARN")
+	}
+}
+
+func TestReadUnknownKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stack.yaml")
+	if err := os.WriteFile(path, []byte("capabilites: [CAPABILITY_IAM]\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := stackconfig.Read(path); err == nil {
+		t.Fatal("expected an error for a misspelled key")
+	}
+}
@@ -0,0 +1,76 @@
+package template
+
+import (
+	"errors"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Document is a parsed CloudFormation template. JSON templates parse too since JSON is a subset of YAML.
+type Document struct {
+	Path string
+	Root *yaml.Node
+}
+
+// Parse parses a YAML or JSON template, keeping the short form intrinsic function tags and the
+// location of every node for error reporting
+func Parse(path string, body []byte) (*Document, error) {
+	root := &yaml.Node{}
+	if err := yaml.Unmarshal(body, root); err != nil {
+		return nil, fmt.Errorf("unable to parse template %s: %w", path, err)
+	}
+
+	if root.Kind != yaml.DocumentNode || len(root.Content) == 0 {
+		return nil, fmt.Errorf("template %s is empty", path)
+	}
+
+	doc := &Document{Path: path, Root: root.Content[0]}
+	if doc.Root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%s:%d:%d: template must be a mapping", path, doc.Root.Line, doc.Root.Column)
+	}
+
+	if doc.Section("Resources") == nil {
+		return nil, errors.New(doc.location(doc.Root) + ": template has no Resources section")
+	}
+
+	return doc, nil
+}
+
+// Section returns the value of a top level section of the template, or nil if it isn't set
+func (d *Document) Section(name string) *yaml.Node {
+	return mappingValue(d.Root, name)
+}
+
+// SectionKeys returns the key nodes of a top level section, such as the names of all resources
+func (d *Document) SectionKeys(name string) []*yaml.Node {
+	section := d.Section(name)
+	if section == nil || section.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	keys := []*yaml.Node{}
+	for i := 0; i+1 < len(section.Content); i += 2 {
+		keys = append(keys, section.Content[i])
+	}
+
+	return keys
+}
+
+func (d *Document) location(node *yaml.Node) string {
+	return fmt.Sprintf("%s:%d:%d", d.Path, node.Line, node.Column)
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+
+	return nil
+}
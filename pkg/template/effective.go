@@ -0,0 +1,77 @@
+package template
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/massdriver-cloud/fogmachine/pkg/redact"
+)
+
+// Redacted replaces the value of NoEcho parameters
+const Redacted = redact.Mask
+
+// EffectiveParameter is the value a parameter ends up with after merging and where it was set
+type EffectiveParameter struct {
+	Name   string
+	Value  string
+	Source string
+}
+
+// EffectiveParameters lists the template's parameters in the order they're declared with the value each
+// one ends up with. NoEcho values are redacted.
+func (o *Output) EffectiveParameters() []EffectiveParameter {
+	set := make(map[string]parameterValue, len(o.values))
+	for _, value := range o.values {
+		set[value.Key] = value
+	}
+
+	definitions := make([]*ParameterDefinition, 0, len(o.Definitions))
+	for _, definition := range o.Definitions {
+		definitions = append(definitions, definition)
+	}
+	sort.Slice(definitions, func(i, j int) bool {
+		return definitions[i].Node.Line < definitions[j].Node.Line
+	})
+
+	effective := []EffectiveParameter{}
+	for _, definition := range definitions {
+		parameter := EffectiveParameter{Name: definition.Name}
+
+		value, ok := set[definition.Name]
+		switch {
+		case ok && value.UsePrevious:
+			parameter.Value = "(previous value)"
+			parameter.Source = value.location()
+		case ok:
+			parameter.Value = value.Value
+			parameter.Source = value.location()
+		case definition.Default != nil:
+			parameter.Value = *definition.Default
+			parameter.Source = "template default"
+		default:
+			continue
+		}
+
+		if definition.NoEcho && !value.UsePrevious {
+			parameter.Value = Redacted
+		}
+
+		effective = append(effective, parameter)
+	}
+
+	return effective
+}
+
+// WriteEffectiveParameters prints the effective parameters as a table
+func (o *Output) WriteEffectiveParameters(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PARAMETER\tVALUE\tSOURCE")
+
+	for _, parameter := range o.EffectiveParameters() {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", parameter.Name, parameter.Value, parameter.Source)
+	}
+
+	return tw.Flush()
+}
@@ -0,0 +1,157 @@
+package template
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The keys of a CodePipeline template configuration file
+var pipelineKeys = map[string]bool{
+	"Parameters":  true,
+	"Tags":        true,
+	"StackPolicy": true,
+}
+
+// normalize detects the format of a parameter file and returns its parameters as a map of names to values.
+// A map is either a CodePipeline template configuration or parameter names to values, a list is either the
+// AWS CLI format or Key=Value strings.
+func (r *valueReader) normalize(root *yaml.Node) (*yaml.Node, error) {
+	switch {
+	case root.Kind == yaml.SequenceNode:
+		return r.parameterList(root), nil
+	case isPipelineConfig(root):
+		return r.pipelineConfig(root), nil
+	case root.Kind == yaml.MappingNode:
+		return root, nil
+	}
+
+	return nil, fmt.Errorf("%s:%d:%d: parameters must be a map of parameter names to values or a list of parameters", r.origin(root), root.Line, root.Column)
+}
+
+// isPipelineConfig reports whether the map has a Parameters object and only CodePipeline keys
+func isPipelineConfig(root *yaml.Node) bool {
+	parameters := mappingValue(root, "Parameters")
+	if parameters == nil || parameters.Kind != yaml.MappingNode {
+		return false
+	}
+
+	for i := 0; i < len(root.Content); i += 2 {
+		if !pipelineKeys[root.Content[i].Value] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (r *valueReader) pipelineConfig(root *yaml.Node) *yaml.Node {
+	if tags := mappingValue(root, "Tags"); tags != nil {
+		r.addTags(tags)
+	}
+
+	if policy := mappingValue(root, "StackPolicy"); policy != nil {
+		r.addStackPolicy(policy)
+	}
+
+	return mappingValue(root, "Parameters")
+}
+
+func (r *valueReader) addTags(node *yaml.Node) {
+	if node.Kind != yaml.MappingNode {
+		r.problem(node, "Tags must be a map of tag names to values")
+		return
+	}
+
+	if r.tags == nil {
+		r.tags = make(map[string]string)
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if value.Kind != yaml.ScalarNode {
+			r.problem(value, "tag %s must be a string", key.Value)
+			continue
+		}
+
+		str, err := scalarString(value)
+		if err != nil {
+			r.problem(value, "tag %s: %s", key.Value, err)
+			continue
+		}
+		r.tags[key.Value] = str
+	}
+}
+
+// addStackPolicy accepts the policy as an object or as a string of JSON
+func (r *valueReader) addStackPolicy(node *yaml.Node) {
+	if node.Kind == yaml.ScalarNode {
+		if !json.Valid([]byte(node.Value)) {
+			r.problem(node, "StackPolicy must be a JSON policy document")
+			return
+		}
+		r.stackPolicy = []byte(node.Value)
+		return
+	}
+
+	var policy interface{}
+	if err := node.Decode(&policy); err != nil {
+		r.problem(node, "StackPolicy: %s", err)
+		return
+	}
+
+	encoded, err := json.Marshal(policy)
+	if err != nil {
+		r.problem(node, "StackPolicy: unable to encode as JSON: %s", err)
+		return
+	}
+
+	r.stackPolicy = encoded
+}
+
+// parameterList reads the AWS CLI [{"ParameterKey": .., "ParameterValue": ..}] format and the
+// aws cloudformation deploy ["Key=Value"] format
+func (r *valueReader) parameterList(root *yaml.Node) *yaml.Node {
+	params := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Line: root.Line, Column: root.Column}
+
+	for _, item := range root.Content {
+		switch {
+		case item.Kind == yaml.MappingNode && mappingValue(item, "ParameterKey") != nil:
+			r.cliParameter(params, item)
+		case item.Kind == yaml.ScalarNode && strings.Contains(item.Value, "="):
+			key, value, _ := strings.Cut(item.Value, "=")
+			node := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value, Line: item.Line, Column: item.Column}
+			r.origins[node] = r.origin(item)
+			setMappingValue(params, key, node)
+		default:
+			r.problem(item, "parameters must be {\"ParameterKey\": .., \"ParameterValue\": ..} objects or Key=Value strings")
+		}
+	}
+
+	return params
+}
+
+func (r *valueReader) cliParameter(params, item *yaml.Node) {
+	key := mappingValue(item, "ParameterKey").Value
+
+	usePrevious := false
+	if previous := mappingValue(item, "UsePreviousValue"); previous != nil {
+		if err := previous.Decode(&usePrevious); err != nil {
+			r.problem(previous, "parameter %s: UsePreviousValue must be true or false", key)
+			return
+		}
+	}
+
+	node := mappingValue(item, "ParameterValue")
+	switch {
+	case usePrevious && node != nil:
+		r.problem(node, "parameter %s: ParameterValue can't be set with UsePreviousValue", key)
+	case usePrevious:
+		r.pending[key] = item
+	case node == nil:
+		r.problem(item, "parameter %s: ParameterValue or UsePreviousValue must be set", key)
+	default:
+		setMappingValue(params, key, node)
+	}
+}
@@ -0,0 +1,196 @@
+package template
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvironmentPrefix marks the environment variables that set parameters, FOGMACHINE_PARAM_BucketName sets
+// BucketName. Nested keys are separated by a double underscore.
+const EnvironmentPrefix = "FOGMACHINE_PARAM_"
+
+// readParameters deep merges the parameter files in order, then the environment variables and then the
+// --parameter overrides, and converts the result to parameter values
+func readParameters(input Input, definitions map[string]*ParameterDefinition) (*valueReader, error) {
+	reader, err := newValueReader(input, definitions)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+
+	for _, path := range input.ParameterPaths {
+		root, readErr := reader.readFile(path)
+		if readErr != nil {
+			return nil, readErr
+		}
+
+		reader.pending = make(map[string]*yaml.Node)
+		params, normalizeErr := reader.normalize(root)
+		if normalizeErr != nil {
+			return nil, normalizeErr
+		}
+		reader.addLayer(merged, params)
+	}
+
+	for _, env := range input.Environment {
+		name, value, ok := strings.Cut(env, "=")
+		if !ok || !strings.HasPrefix(name, EnvironmentPrefix) || name == EnvironmentPrefix {
+			continue
+		}
+		key := strings.ReplaceAll(strings.TrimPrefix(name, EnvironmentPrefix), "__", ".")
+		reader.override(merged, key, value, name)
+	}
+
+	for _, override := range input.Overrides {
+		key, value, ok := strings.Cut(override, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("parameter %q must be in the form Key=Value", override)
+		}
+		reader.override(merged, key, value, "--parameter "+key)
+	}
+
+	if err = reader.addParameterMap(merged); err != nil {
+		return nil, err
+	}
+
+	reader.addPrevious()
+
+	return reader, nil
+}
+
+// readFile parses a parameter file and records where each node came from. An empty file has no parameters.
+func (r *valueReader) readFile(path string) (*yaml.Node, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// JSON is parsed as YAML so every value keeps its location in the file
+	root := &yaml.Node{}
+	if err = yaml.Unmarshal(data, root); err != nil {
+		return nil, fmt.Errorf("unable to parse parameters %s: %w", path, err)
+	}
+
+	if len(root.Content) == 0 {
+		return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}, nil
+	}
+
+	r.recordOrigin(root.Content[0], path)
+
+	return root.Content[0], nil
+}
+
+func (r *valueReader) recordOrigin(node *yaml.Node, origin string) {
+	r.origins[node] = origin
+	for _, child := range node.Content {
+		r.recordOrigin(child, origin)
+	}
+}
+
+// origin returns where a node was read from. Nodes built while reading, like the list a wildcard
+// selector returns, take the origin of their first child.
+func (r *valueReader) origin(node *yaml.Node) string {
+	if origin, ok := r.origins[node]; ok {
+		return origin
+	}
+
+	if len(node.Content) > 0 {
+		return r.origin(node.Content[0])
+	}
+
+	return "parameters"
+}
+
+// addLayer merges a parameter file on top of the ones before it. Setting a value replaces an earlier
+// UsePreviousValue and the other way around.
+func (r *valueReader) addLayer(merged, params *yaml.Node) {
+	for i := 0; i+1 < len(params.Content); i += 2 {
+		delete(r.previous, params.Content[i].Value)
+	}
+
+	for key, node := range r.pending {
+		deleteMappingValue(merged, key)
+		r.previous[key] = node
+	}
+
+	mergeMappings(merged, params)
+}
+
+// mergeMappings deep merges src into dst, objects are merged key by key and anything else is replaced
+func mergeMappings(dst, src *yaml.Node) {
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		key, value := src.Content[i].Value, src.Content[i+1]
+		if value.Kind == yaml.AliasNode {
+			value = value.Alias
+		}
+
+		if existing := mappingValue(dst, key); existing != nil && existing.Kind == yaml.MappingNode && value.Kind == yaml.MappingNode {
+			mergeMappings(existing, value)
+			continue
+		}
+
+		setMappingValue(dst, key, value)
+	}
+}
+
+// override sets a single value, a dotted key sets a nested value
+func (r *valueReader) override(merged *yaml.Node, key, value, origin string) {
+	parts := strings.Split(key, ".")
+
+	parent := merged
+	for _, part := range parts[:len(parts)-1] {
+		child := mappingValue(parent, part)
+		if child == nil || child.Kind != yaml.MappingNode {
+			child = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			r.origins[child] = origin
+			setMappingValue(parent, part, child)
+		}
+		parent = child
+	}
+
+	// Overrides are always strings, --parameter Enabled=true doesn't need converting
+	node := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+	r.origins[node] = origin
+	setMappingValue(parent, parts[len(parts)-1], node)
+
+	delete(r.previous, key)
+}
+
+// addPrevious adds the parameters that keep the value the stack already has
+func (r *valueReader) addPrevious() {
+	keys := make([]string, 0, len(r.previous))
+	for key := range r.previous {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		node := r.previous[key]
+		r.values = append(r.values, parameterValue{Key: key, Source: key, UsePrevious: true, Path: r.origin(node), Node: node})
+	}
+}
+
+func setMappingValue(node *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			node.Content[i+1] = value
+			return
+		}
+	}
+
+	node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+}
+
+func deleteMappingValue(node *yaml.Node, key string) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			node.Content = append(node.Content[:i], node.Content[i+2:]...)
+			return
+		}
+	}
+}
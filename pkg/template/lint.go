@@ -0,0 +1,244 @@
+package template
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Problem is a mistake found in a template without calling CloudFormation
+type Problem struct {
+	Severity Severity `json:"severity"`
+	Path     string   `json:"path"`
+	Line     int      `json:"line"`
+	Column   int      `json:"column"`
+	Message  string   `json:"message"`
+}
+
+func (p Problem) String() string {
+	// Parameters set by environment variables and flags don't have a line
+	if p.Line == 0 {
+		return fmt.Sprintf("%s: %s: %s", p.Path, p.Severity, p.Message)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s: %s", p.Path, p.Line, p.Column, p.Severity, p.Message)
+}
+
+var shortFormTags = map[string]bool{
+	"!And": true, "!Base64": true, "!Cidr": true, "!Condition": true, "!Equals": true,
+	"!FindInMap": true, "!GetAtt": true, "!GetAZs": true, "!If": true, "!ImportValue": true,
+	"!Join": true, "!Length": true, "!Not": true, "!Or": true, "!Ref": true, "!Select": true,
+	"!Split": true, "!Sub": true, "!ToJsonString": true, "!Transform": true,
+}
+
+var pseudoParameters = map[string]bool{
+	"AWS::AccountId": true, "AWS::NotificationARNs": true, "AWS::NoValue": true, "AWS::Partition": true,
+	"AWS::Region": true, "AWS::StackId": true, "AWS::StackName": true, "AWS::URLSuffix": true,
+}
+
+// subVariable matches ${Name} and ${Name.Attribute} but not the ${!Literal} escape
+var subVariable = regexp.MustCompile(`\$\{([^!}][^}]*)\}`)
+
+type linter struct {
+	doc        *Document
+	parameters map[string]*yaml.Node
+	resources  map[string]bool
+	used       map[string]bool
+	problems   []Problem
+	// Transforms such as SAM generate resources, so targets we can't see are only a warning
+	transformed bool
+}
+
+// Lint checks the template for unknown intrinsic function tags, Ref, GetAtt and Sub targets that don't
+// exist and parameters that are never used
+func (d *Document) Lint() []Problem {
+	l := &linter{
+		doc:         d,
+		parameters:  make(map[string]*yaml.Node),
+		resources:   make(map[string]bool),
+		used:        make(map[string]bool),
+		problems:    []Problem{},
+		transformed: d.Section("Transform") != nil,
+	}
+
+	for _, key := range d.SectionKeys("Parameters") {
+		l.parameters[key.Value] = key
+	}
+
+	for _, key := range d.SectionKeys("Resources") {
+		l.resources[key.Value] = true
+	}
+
+	l.walk(d.Root, nil)
+
+	for name, key := range l.parameters {
+		if !l.used[name] {
+			l.report(SeverityWarning, key, "parameter %s is never used", name)
+		}
+	}
+
+	sort.SliceStable(l.problems, func(i, j int) bool {
+		if l.problems[i].Line != l.problems[j].Line {
+			return l.problems[i].Line < l.problems[j].Line
+		}
+		return l.problems[i].Column < l.problems[j].Column
+	})
+
+	return l.problems
+}
+
+func (l *linter) report(severity Severity, node *yaml.Node, format string, args ...interface{}) {
+	l.problems = append(l.problems, Problem{
+		Severity: severity,
+		Path:     l.doc.Path,
+		Line:     node.Line,
+		Column:   node.Column,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// walk visits every node, with locals holding the variables defined by an enclosing Fn::Sub
+func (l *linter) walk(node *yaml.Node, locals map[string]bool) {
+	if node == nil {
+		return
+	}
+
+	if strings.HasPrefix(node.Tag, "!") && !strings.HasPrefix(node.Tag, "!!") {
+		if !shortFormTags[node.Tag] {
+			l.report(SeverityError, node, "unknown intrinsic function tag %s", node.Tag)
+		}
+
+		if l.function(strings.TrimPrefix(node.Tag, "!"), node, locals) {
+			return
+		}
+	}
+
+	if node.Kind == yaml.MappingNode && len(node.Content) == 2 {
+		key := node.Content[0].Value
+		if (key == "Ref" || strings.HasPrefix(key, "Fn::")) && l.function(strings.TrimPrefix(key, "Fn::"), node.Content[1], locals) {
+			return
+		}
+	}
+
+	if node.Kind == yaml.AliasNode {
+		l.walk(node.Alias, locals)
+		return
+	}
+
+	for _, child := range node.Content {
+		l.walk(child, locals)
+	}
+}
+
+// function checks the arguments of Ref, GetAtt and Sub. It returns true if it visited the arguments itself.
+func (l *linter) function(name string, args *yaml.Node, locals map[string]bool) bool {
+	switch name {
+	case "Ref":
+		if args.Kind == yaml.ScalarNode {
+			l.ref(args, args.Value, locals)
+			return true
+		}
+	case "GetAtt":
+		l.getAtt(args)
+	case "Sub":
+		return l.sub(args, locals)
+	}
+
+	return false
+}
+
+func (l *linter) ref(node *yaml.Node, target string, locals map[string]bool) {
+	switch {
+	case locals[target]:
+	case strings.HasPrefix(target, "AWS::"):
+		if !pseudoParameters[target] {
+			l.report(SeverityError, node, "unknown pseudo parameter %s", target)
+		}
+	case l.parameters[target] != nil:
+		l.used[target] = true
+	case l.resources[target]:
+	default:
+		l.report(l.missingSeverity(), node, "reference to %s, which is not a parameter or resource", target)
+	}
+}
+
+func (l *linter) getAtt(args *yaml.Node) {
+	var target *yaml.Node
+	var resource string
+
+	switch args.Kind {
+	case yaml.ScalarNode:
+		target = args
+		resource, _, _ = strings.Cut(args.Value, ".")
+	case yaml.SequenceNode:
+		if len(args.Content) == 0 || args.Content[0].Kind != yaml.ScalarNode || args.Content[0].Tag != "!!str" {
+			return
+		}
+		target = args.Content[0]
+		resource = target.Value
+	case yaml.DocumentNode, yaml.MappingNode, yaml.AliasNode:
+		return
+	}
+
+	if target != nil && !l.resources[resource] {
+		l.report(l.missingSeverity(), target, "GetAtt of %s, which is not a resource", resource)
+	}
+}
+
+func (l *linter) sub(args *yaml.Node, locals map[string]bool) bool {
+	str := args
+	scoped := locals
+
+	if args.Kind == yaml.SequenceNode {
+		if len(args.Content) == 0 {
+			return false
+		}
+
+		str = args.Content[0]
+		scoped = make(map[string]bool)
+		for name := range locals {
+			scoped[name] = true
+		}
+
+		if len(args.Content) > 1 {
+			vars := args.Content[1]
+			for i := 0; i+1 < len(vars.Content); i += 2 {
+				scoped[vars.Content[i].Value] = true
+				l.walk(vars.Content[i+1], locals)
+			}
+		}
+	}
+
+	if str.Kind != yaml.ScalarNode {
+		return false
+	}
+
+	for _, match := range subVariable.FindAllStringSubmatch(str.Value, -1) {
+		name, attribute, isAttribute := strings.Cut(strings.TrimSpace(match[1]), ".")
+		if isAttribute {
+			if !scoped[name] && !l.resources[name] {
+				l.report(l.missingSeverity(), str, "Sub variable ${%s.%s} is not an attribute of a resource", name, attribute)
+			}
+			continue
+		}
+		l.ref(str, strings.TrimSpace(match[1]), scoped)
+	}
+
+	return true
+}
+
+func (l *linter) missingSeverity() Severity {
+	if l.transformed {
+		return SeverityWarning
+	}
+	return SeverityError
+}
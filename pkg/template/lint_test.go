@@ -0,0 +1,70 @@
+package template_test
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/massdriver-cloud/fogmachine/pkg/template"
+)
+
+func TestLint(t *testing.T) {
+	body, err := os.ReadFile("testdata/lint.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := template.Parse("testdata/lint.yaml", body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := []string{}
+	for _, problem := range doc.Lint() {
+		got = append(got, problem.String())
+	}
+
+	want := []string{
+		"testdata/lint.yaml:4:3: warning: parameter Unused is never used",
+		"testdata/lint.yaml:15:18: error: reference to MissingParam, which is not a parameter or resource",
+		"testdata/lint.yaml:17:18: error: unknown intrinsic function tag !GetAtz",
+		"testdata/lint.yaml:28:40: error: GetAtt of OtherBucket, which is not a resource",
+		"testdata/lint.yaml:29:23: error: unknown pseudo parameter AWS::Regoin",
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Got %#v but expected %#v", got, want)
+	}
+}
+
+func TestLintClean(t *testing.T) {
+	body, err := os.ReadFile("testdata/s3.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := template.Parse("testdata/s3.yaml", body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if problems := doc.Lint(); len(problems) != 0 {
+		t.Fatalf("Got %v but expected no problems", problems)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := map[string]string{
+		"syntax":       "Resources:\n  Bucket: [\n",
+		"no resources": "Parameters: {}\n",
+		"not a map":    "- Resources\n",
+	}
+
+	for name, body := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := template.Parse("template.yaml", []byte(body)); err == nil {
+				t.Fatal("expected a parse error")
+			}
+		})
+	}
+}
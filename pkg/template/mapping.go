@@ -0,0 +1,214 @@
+package template
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MappingStrategy controls how nested values in the parameter file are matched to template parameters
+type MappingStrategy string
+
+const (
+	// MappingPascal concatenates the nested keys in PascalCase, connections.auth.role_arn sets ConnectionsAuthRoleArn
+	MappingPascal MappingStrategy = "pascal"
+	// MappingTable renames nested keys with a mapping file of dotted keys to parameter names
+	MappingTable MappingStrategy = "table"
+	// MappingJSONPath selects values with a mapping file of parameter names to selectors such as $.connections.vpc.data.id
+	MappingJSONPath MappingStrategy = "jsonpath"
+)
+
+// Mapping records the template parameter a nested value was set on
+type Mapping struct {
+	Source    string
+	Parameter string
+}
+
+// ParseMappingStrategy parses the --parameter-mapping flag, an empty value is MappingPascal
+func ParseMappingStrategy(strategy string) (MappingStrategy, error) {
+	switch s := MappingStrategy(strategy); s {
+	case "", MappingPascal:
+		return MappingPascal, nil
+	case MappingTable, MappingJSONPath:
+		return s, nil
+	}
+
+	return "", fmt.Errorf("unknown parameter mapping %q, expected one of pascal, table or jsonpath", strategy)
+}
+
+// mappingEntry is a line of the mapping file along with where it was set
+type mappingEntry struct {
+	Key   string
+	Value string
+	Node  *yaml.Node
+}
+
+func readMappingFile(path string) ([]mappingEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	root := &yaml.Node{}
+	if err = yaml.Unmarshal(data, root); err != nil {
+		return nil, fmt.Errorf("unable to parse parameter mapping %s: %w", path, err)
+	}
+
+	entries := []mappingEntry{}
+	if len(root.Content) == 0 {
+		return entries, nil
+	}
+
+	node := root.Content[0]
+	if node.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%s:%d:%d: parameter mapping must be a map", path, node.Line, node.Column)
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if value.Kind != yaml.ScalarNode {
+			return nil, fmt.Errorf("%s:%d:%d: mapping for %s must be a string", path, value.Line, value.Column, key.Value)
+		}
+		entries = append(entries, mappingEntry{Key: key.Value, Value: value.Value, Node: value})
+	}
+
+	return entries, nil
+}
+
+// pascalCase joins the parts of a dotted key, splitting on underscores and dashes too
+func pascalCase(key string) string {
+	var b strings.Builder
+	for _, part := range strings.FieldsFunc(key, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		runes := []rune(part)
+		runes[0] = unicode.ToUpper(runes[0])
+		b.WriteString(string(runes))
+	}
+	return b.String()
+}
+
+type selectorStep struct {
+	field    string
+	index    int
+	wildcard bool
+}
+
+// parseSelector parses the JSONPath subset the jsonpath mapping supports: $.field, $['field'], [0] and [*]
+func parseSelector(selector string) ([]selectorStep, error) {
+	if !strings.HasPrefix(selector, "$") {
+		return nil, fmt.Errorf("selector %q must start with $", selector)
+	}
+
+	steps := []selectorStep{}
+	rest := selector[1:]
+	for rest != "" {
+		var step selectorStep
+		var err error
+
+		switch rest[0] {
+		case '.':
+			end := strings.IndexAny(rest[1:], ".[") + 1
+			if end == 0 {
+				end = len(rest)
+			}
+			step = selectorStep{field: rest[1:end], index: -1}
+			rest = rest[end:]
+		case '[':
+			end := strings.Index(rest, "]")
+			if end == -1 {
+				return nil, fmt.Errorf("selector %q has an unclosed [", selector)
+			}
+			step, err = parseBracket(rest[1:end])
+			rest = rest[end+1:]
+		default:
+			err = fmt.Errorf("unexpected %q", rest[0])
+		}
+
+		if err == nil && step.field == "" && step.index < 0 {
+			err = errors.New("empty field name")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("selector %q is invalid: %w", selector, err)
+		}
+		steps = append(steps, step)
+	}
+
+	return steps, nil
+}
+
+func parseBracket(inner string) (selectorStep, error) {
+	if inner == "*" {
+		return selectorStep{wildcard: true}, nil
+	}
+
+	if len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0] {
+		return selectorStep{field: inner[1 : len(inner)-1], index: -1}, nil
+	}
+
+	index, err := strconv.Atoi(inner)
+	if err != nil || index < 0 {
+		return selectorStep{index: -1}, fmt.Errorf("%q is not a field, index or *", inner)
+	}
+
+	return selectorStep{index: index}, nil
+}
+
+// selectNodes returns the nodes the selector matches. Selectors with a wildcard always return a sequence.
+func selectNodes(root *yaml.Node, steps []selectorStep) (*yaml.Node, bool) {
+	nodes := []*yaml.Node{root}
+	wildcard := false
+
+	for _, step := range steps {
+		next := []*yaml.Node{}
+		for _, node := range nodes {
+			next = append(next, step.apply(node)...)
+		}
+		nodes = next
+		wildcard = wildcard || step.wildcard
+	}
+
+	if wildcard {
+		sequence := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Content: nodes, Line: root.Line, Column: root.Column}
+		if len(nodes) > 0 {
+			sequence.Line, sequence.Column = nodes[0].Line, nodes[0].Column
+		}
+		return sequence, true
+	}
+
+	if len(nodes) == 0 {
+		return nil, false
+	}
+
+	return nodes[0], true
+}
+
+func (s selectorStep) apply(node *yaml.Node) []*yaml.Node {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+
+	switch {
+	case s.wildcard && node.Kind == yaml.SequenceNode:
+		return node.Content
+	case s.wildcard && node.Kind == yaml.MappingNode:
+		values := []*yaml.Node{}
+		for i := 1; i < len(node.Content); i += 2 {
+			values = append(values, node.Content[i])
+		}
+		return values
+	case s.field != "":
+		if value := mappingValue(node, s.field); value != nil {
+			return []*yaml.Node{value}
+		}
+	case node.Kind == yaml.SequenceNode && s.index < len(node.Content):
+		return []*yaml.Node{node.Content[s.index]}
+	}
+
+	return nil
+}
@@ -0,0 +1,204 @@
+package template
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ParameterDefinition is a parameter declared in the Parameters section of a template
+type ParameterDefinition struct {
+	Name           string
+	Type           string
+	Default        *string
+	AllowedValues  []string
+	AllowedPattern string
+	MinLength      *int
+	MaxLength      *int
+	MinValue       *float64
+	MaxValue       *float64
+	NoEcho         bool
+	Node           *yaml.Node
+}
+
+type parameterSpec struct {
+	Type           string    `yaml:"Type"`
+	Default        yaml.Node `yaml:"Default"`
+	AllowedValues  []string  `yaml:"AllowedValues"`
+	AllowedPattern string    `yaml:"AllowedPattern"`
+	MinLength      string    `yaml:"MinLength"`
+	MaxLength      string    `yaml:"MaxLength"`
+	MinValue       string    `yaml:"MinValue"`
+	MaxValue       string    `yaml:"MaxValue"`
+	NoEcho         string    `yaml:"NoEcho"`
+}
+
+// Parameters returns the parameters declared by the template, keyed by name
+func (d *Document) Parameters() (map[string]*ParameterDefinition, error) {
+	definitions := make(map[string]*ParameterDefinition)
+
+	section := d.Section("Parameters")
+	if section == nil {
+		return definitions, nil
+	}
+
+	for i := 0; i+1 < len(section.Content); i += 2 {
+		key, value := section.Content[i], section.Content[i+1]
+
+		spec := parameterSpec{}
+		if err := value.Decode(&spec); err != nil {
+			return nil, fmt.Errorf("%s: parameter %s: %w", d.location(value), key.Value, err)
+		}
+
+		definition, err := spec.definition(key)
+		if err != nil {
+			return nil, fmt.Errorf("%s: parameter %s: %w", d.location(value), key.Value, err)
+		}
+
+		definitions[key.Value] = definition
+	}
+
+	return definitions, nil
+}
+
+func (s parameterSpec) definition(key *yaml.Node) (*ParameterDefinition, error) {
+	definition := &ParameterDefinition{
+		Name:           key.Value,
+		Type:           s.Type,
+		AllowedValues:  s.AllowedValues,
+		AllowedPattern: s.AllowedPattern,
+		NoEcho:         strings.EqualFold(s.NoEcho, "true"),
+		Node:           key,
+	}
+
+	// An unset Default decodes to the zero node, which is different from Default: ""
+	if s.Default.Kind != 0 {
+		definition.Default = &s.Default.Value
+	}
+
+	var err error
+	if s.MinLength != "" {
+		if definition.MinLength, err = parseInt("MinLength", s.MinLength); err != nil {
+			return nil, err
+		}
+	}
+	if s.MaxLength != "" {
+		if definition.MaxLength, err = parseInt("MaxLength", s.MaxLength); err != nil {
+			return nil, err
+		}
+	}
+	if s.MinValue != "" {
+		if definition.MinValue, err = parseFloat("MinValue", s.MinValue); err != nil {
+			return nil, err
+		}
+	}
+	if s.MaxValue != "" {
+		if definition.MaxValue, err = parseFloat("MaxValue", s.MaxValue); err != nil {
+			return nil, err
+		}
+	}
+
+	if definition.AllowedPattern != "" {
+		if _, err = regexp.Compile(definition.AllowedPattern); err != nil {
+			return nil, fmt.Errorf("AllowedPattern is not a valid regular expression: %w", err)
+		}
+	}
+
+	return definition, nil
+}
+
+// IsList reports whether the parameter takes a comma delimited list of values
+func (p *ParameterDefinition) IsList() bool {
+	return p.Type == "CommaDelimitedList" || strings.HasPrefix(p.Type, "List<")
+}
+
+func parseInt(name, value string) (*int, error) {
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be an integer, got %q", name, value)
+	}
+
+	return &i, nil
+}
+
+func parseFloat(name, value string) (*float64, error) {
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be a number, got %q", name, value)
+	}
+
+	return &f, nil
+}
+
+// Check returns why value isn't allowed for the parameter, or nothing if it is. The elements of list
+// parameters are checked individually.
+func (p *ParameterDefinition) Check(value string) []string {
+	if !p.IsList() {
+		return p.checkValue(value, p.Type)
+	}
+
+	elementType := strings.TrimSuffix(strings.TrimPrefix(p.Type, "List<"), ">")
+
+	problems := []string{}
+	for _, element := range strings.Split(value, ",") {
+		problems = append(problems, p.checkValue(strings.TrimSpace(element), elementType)...)
+	}
+
+	return problems
+}
+
+func (p *ParameterDefinition) checkValue(value, valueType string) []string {
+	problems := []string{}
+
+	if len(p.AllowedValues) > 0 && !contains(p.AllowedValues, value) {
+		problems = append(problems, fmt.Sprintf("value %q is not one of the allowed values %v", value, p.AllowedValues))
+	}
+
+	// CloudFormation requires the pattern to match the whole value
+	if p.AllowedPattern != "" && !regexp.MustCompile("^(?:"+p.AllowedPattern+")$").MatchString(value) {
+		problems = append(problems, fmt.Sprintf("value %q does not match the allowed pattern %s", value, p.AllowedPattern))
+	}
+
+	if p.MinLength != nil && len(value) < *p.MinLength {
+		problems = append(problems, fmt.Sprintf("value is shorter than the minimum length of %d", *p.MinLength))
+	}
+
+	if p.MaxLength != nil && len(value) > *p.MaxLength {
+		problems = append(problems, fmt.Sprintf("value is longer than the maximum length of %d", *p.MaxLength))
+	}
+
+	if valueType != "Number" {
+		return problems
+	}
+
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return append(problems, fmt.Sprintf("value %q is not a number", value))
+	}
+
+	if p.MinValue != nil && number < *p.MinValue {
+		problems = append(problems, fmt.Sprintf("value %s is less than the minimum value of %s", value, formatFloat(*p.MinValue)))
+	}
+
+	if p.MaxValue != nil && number > *p.MaxValue {
+		problems = append(problems, fmt.Sprintf("value %s is greater than the maximum value of %s", value, formatFloat(*p.MaxValue)))
+	}
+
+	return problems
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
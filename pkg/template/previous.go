@@ -0,0 +1,63 @@
+package template
+
+import (
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PreviousValues decides which parameters that aren't set keep the value they have on the existing stack
+type PreviousValues struct {
+	// Omitted keeps the previous value of every parameter that isn't set
+	Omitted bool
+	// Keep opts single parameters in when Omitted is false
+	Keep []string
+	// Reset opts single parameters out, they fall back to the template default
+	Reset []string
+}
+
+func (p PreviousValues) keeps(name string) bool {
+	if contains(p.Reset, name) {
+		return false
+	}
+	return p.Omitted || contains(p.Keep, name)
+}
+
+// keepPreviousValues sends UsePreviousValue for the parameters of the existing stack that aren't set. It does
+// nothing when the existing parameters weren't looked up.
+func keepPreviousValues(input Input, definitions map[string]*ParameterDefinition, values []parameterValue) ([]parameterValue, []Problem) {
+	if input.ExistingParameters == nil {
+		return values, nil
+	}
+
+	existing := make(map[string]bool, len(input.ExistingParameters))
+	for _, name := range input.ExistingParameters {
+		existing[name] = true
+	}
+
+	problems := []Problem{}
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[value.Key] = true
+		if value.UsePrevious && !existing[value.Key] {
+			problems = append(problems, value.problem("parameter %s can't use its previous value, the stack doesn't have one", value.label()))
+		}
+	}
+
+	names := make([]string, 0, len(definitions))
+	for name := range definitions {
+		if !set[name] && existing[name] && input.Previous.keeps(name) {
+			names = append(names, name)
+		}
+	}
+
+	sort.Slice(names, func(i, j int) bool {
+		return definitions[names[i]].Node.Line < definitions[names[j]].Node.Line
+	})
+
+	for _, name := range names {
+		values = append(values, parameterValue{Key: name, Source: name, UsePrevious: true, Path: "existing stack", Node: &yaml.Node{}})
+	}
+
+	return values, problems
+}
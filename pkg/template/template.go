@@ -1,22 +1,72 @@
 package template
 
 import (
-	"encoding/json"
+	"fmt"
 	"os"
-	"strconv"
+	"sort"
+	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
 )
 
 type Input struct {
-	TemplatePath  string
-	ParameterPath string
+	TemplatePath string
+	// ParameterPaths are deep merged in order, later files override earlier ones
+	ParameterPaths []string
+	// Environment is searched for FOGMACHINE_PARAM_ variables, which override the files
+	Environment []string
+	// Overrides are Key=Value pairs that override everything else
+	Overrides    []string
+	NestedPolicy NestedPolicy
+	Mapping      MappingStrategy
+	MappingPath  string
+	// ExistingParameters are the names of the parameters on the stack being updated, empty for a new
+	// stack. Previous values are only used when they're set.
+	ExistingParameters []string
+	Previous           PreviousValues
 }
 
 type Output struct {
-	Template   []byte
-	Parameters []types.Parameter
+	Template    []byte
+	Document    *Document
+	Definitions map[string]*ParameterDefinition
+	Parameters  []types.Parameter
+	// Tags and StackPolicy are set when the parameter file is a CodePipeline template configuration
+	Tags        map[string]string
+	StackPolicy []byte
+	// Mappings lists the nested values that were set on a differently named parameter
+	Mappings []Mapping
+	// Secrets are the values and defaults of NoEcho parameters, they should be redacted from anything written out
+	Secrets []string
+
+	values []parameterValue
+}
+
+// ParameterError lists every problem found cross checking the parameter file with the template
+type ParameterError struct {
+	Problems []Problem
+}
+
+func (e *ParameterError) Error() string {
+	lines := make([]string, 0, len(e.Problems))
+	for _, problem := range e.Problems {
+		lines = append(lines, problem.String())
+	}
+	return "invalid parameters:\n" + strings.Join(lines, "\n")
+}
+
+// parameterValue is a value from the parameter file along with where it was set. Source is the
+// flattened key or selector the value was read from when it differs from the parameter name.
+type parameterValue struct {
+	Key         string
+	Source      string
+	Value       string
+	UsePrevious bool
+	Path        string
+	Node        *yaml.Node
 }
 
 func Read(input Input) (*Output, error) {
@@ -25,59 +75,190 @@ func Read(input Input) (*Output, error) {
 		return nil, err
 	}
 
+	doc, err := Parse(input.TemplatePath, template)
+	if err != nil {
+		return nil, err
+	}
+
+	definitions, err := doc.Parameters()
+	if err != nil {
+		return nil, err
+	}
+
 	output := &Output{
-		Template: template,
+		Template:    template,
+		Document:    doc,
+		Definitions: definitions,
 	}
 
-	params, err := readParameters(input.ParameterPath)
+	reader, err := readParameters(input, definitions)
 	if err != nil {
 		return nil, err
 	}
 
-	output.Parameters = params
+	values, previousProblems := keepPreviousValues(input, definitions, reader.values)
+	problems := reader.problems
+	problems = append(problems, previousProblems...)
+	problems = append(problems, checkParameters(doc, definitions, values)...)
+	if len(problems) > 0 {
+		sortProblems(doc, problems)
+		return nil, &ParameterError{Problems: problems}
+	}
+
+	output.Parameters = toParameters(values)
+	output.Secrets = secretValues(definitions, values)
+	output.values = values
+	output.Tags = reader.tags
+	output.StackPolicy = reader.stackPolicy
+
+	for _, value := range values {
+		if value.Source != value.Key {
+			output.Mappings = append(output.Mappings, Mapping{Source: value.Source, Parameter: value.Key})
+		}
+	}
 
 	return output, nil
 }
 
-func readParameters(filePath string) ([]types.Parameter, error) {
-	parameters := []types.Parameter{}
-	rawParameters, err := os.ReadFile(filePath)
-	if err != nil {
-		return parameters, err
+// checkParameters reports unknown parameters, values the template doesn't allow and
+// parameters without a default that aren't set
+func checkParameters(doc *Document, definitions map[string]*ParameterDefinition, values []parameterValue) []Problem {
+	problems := []Problem{}
+	set := make(map[string]bool)
+
+	for _, value := range values {
+		if set[value.Key] {
+			problems = append(problems, value.problem("parameter %s is set more than once", value.label()))
+			continue
+		}
+		set[value.Key] = true
+
+		definition, ok := definitions[value.Key]
+		if !ok {
+			problems = append(problems, value.problem("parameter %s is not declared in the template", value.label()))
+			continue
+		}
+
+		// The previous value was checked when it was set
+		if value.UsePrevious {
+			continue
+		}
+
+		for _, message := range definition.Check(value.Value) {
+			problems = append(problems, value.problem("parameter %s: %s", value.label(), message))
+		}
 	}
 
-	rawJSON := make(map[string]interface{})
-	err = json.Unmarshal(rawParameters, &rawJSON)
-	if err != nil {
-		return parameters, err
+	missing := []*ParameterDefinition{}
+	for name, definition := range definitions {
+		if !set[name] && definition.Default == nil {
+			missing = append(missing, definition)
+		}
+	}
+
+	sort.Slice(missing, func(i, j int) bool {
+		return missing[i].Node.Line < missing[j].Node.Line
+	})
+
+	for _, definition := range missing {
+		problems = append(problems, Problem{
+			Severity: SeverityError,
+			Path:     doc.Path,
+			Line:     definition.Node.Line,
+			Column:   definition.Node.Column,
+			Message:  fmt.Sprintf("parameter %s has no default and is not set", definition.Name),
+		})
 	}
 
-	flattenedParams := make(map[string]interface{})
+	return problems
+}
+
+// sortProblems orders problems in the parameter files by location, followed by the ones in the template
+func sortProblems(doc *Document, problems []Problem) {
+	sort.SliceStable(problems, func(i, j int) bool {
+		a, b := problems[i], problems[j]
+		if (a.Path == doc.Path) != (b.Path == doc.Path) {
+			return b.Path == doc.Path
+		}
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+}
 
-	flattenNestedParams("", rawJSON, flattenedParams)
+// label names the parameter in problems, along with where it was mapped from
+func (v parameterValue) label() string {
+	if v.Source == "" || v.Source == v.Key {
+		return v.Key
+	}
+	return fmt.Sprintf("%s (from %s)", v.Key, v.Source)
+}
 
-	for key, value := range flattenedParams {
-		p := types.Parameter{ParameterKey: aws.String(key), ParameterValue: aws.String(value.(string))}
-		parameters = append(parameters, p)
+// location is where the value was set, file:line:column for files
+func (v parameterValue) location() string {
+	if v.Node.Line == 0 {
+		return v.Path
 	}
+	return fmt.Sprintf("%s:%d:%d", v.Path, v.Node.Line, v.Node.Column)
+}
 
-	return parameters, nil
+func (v parameterValue) problem(format string, args ...interface{}) Problem {
+	return problemAt(v.Path, v.Node, format, args...)
 }
 
-func flattenNestedParams(prefix string, src map[string]interface{}, dest map[string]interface{}) {
-	if len(prefix) > 0 {
-		prefix += "."
-	}
-	for k, v := range src {
-		switch child := v.(type) {
-		case map[string]interface{}:
-			flattenNestedParams(prefix+k, child, dest)
-		case []interface{}:
-			for i := 0; i < len(child); i++ {
-				dest[prefix+k+"."+strconv.Itoa(i)] = child[i]
-			}
-		default:
-			dest[prefix+k] = v
+func problemAt(path string, node *yaml.Node, format string, args ...interface{}) Problem {
+	return Problem{
+		Severity: SeverityError,
+		Path:     path,
+		Line:     node.Line,
+		Column:   node.Column,
+		Message:  fmt.Sprintf(format, args...),
+	}
+}
+
+// secretValues returns the values and defaults of NoEcho parameters, list values are also split into their items
+func secretValues(definitions map[string]*ParameterDefinition, values []parameterValue) []string {
+	secrets := []string{}
+	for _, definition := range definitions {
+		if definition.NoEcho && definition.Default != nil {
+			secrets = append(secrets, *definition.Default)
+		}
+	}
+
+	for _, value := range values {
+		definition, ok := definitions[value.Key]
+		if !ok || !definition.NoEcho || value.UsePrevious {
+			continue
 		}
+
+		secrets = append(secrets, value.Value)
+		if definition.IsList() {
+			secrets = append(secrets, strings.Split(value.Value, ",")...)
+		}
+	}
+
+	return secrets
+}
+
+func toParameters(values []parameterValue) []types.Parameter {
+	parameters := []types.Parameter{}
+	for _, value := range values {
+		if value.UsePrevious {
+			parameters = append(parameters, types.Parameter{ParameterKey: aws.String(value.Key), UsePreviousValue: aws.Bool(true)})
+			continue
+		}
+		parameters = append(parameters, types.Parameter{ParameterKey: aws.String(value.Key), ParameterValue: aws.String(value.Value)})
+	}
+	return parameters
+}
+
+// LogMappings reports which template parameter each nested value was set on
+func (o *Output) LogMappings() {
+	for _, mapping := range o.Mappings {
+		log.Info().Str("source", mapping.Source).Str("parameter", mapping.Parameter).Msg("Mapped nested parameter")
 	}
 }
@@ -1,17 +1,20 @@
 package template_test
 
 import (
+	"errors"
 	"os"
 	"reflect"
 	"testing"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
 	"github.com/massdriver-cloud/fogmachine/pkg/template"
 )
 
 func TestParseConfig(t *testing.T) {
 	input := template.Input{
-		TemplatePath:  "testdata/s3.yaml",
-		ParameterPath: "testdata/s3-values.json",
+		TemplatePath:   "testdata/s3.yaml",
+		ParameterPaths: []string{"testdata/s3-values.json"},
 	}
 
 	got, err := template.Read(input)
@@ -29,9 +32,13 @@ func TestParseConfig(t *testing.T) {
 	}
 
 	wantParameters := map[string]string{
-		"BucketName":       "md-test-cf-1234",
-		"DevBucketName":    "md-test-dev-cf-1234",
-		"connections.auth": "test",
+		"BucketName":      "md-test-cf-1234",
+		"DevBucketName":   "md-test-dev-cf-1234",
+		"ConnectionsAuth": "test",
+	}
+
+	if len(got.Parameters) != len(wantParameters) {
+		t.Fatalf("Got %d parameters but expected %d", len(got.Parameters), len(wantParameters))
 	}
 
 	for _, parameter := range got.Parameters {
@@ -41,4 +48,323 @@ func TestParseConfig(t *testing.T) {
 			t.Fatalf("Got %s but expected %s", got, want)
 		}
 	}
+
+	wantMappings := []template.Mapping{{Source: "connections.auth", Parameter: "ConnectionsAuth"}}
+	if !reflect.DeepEqual(got.Mappings, wantMappings) {
+		t.Fatalf("Got %v but expected %v", got.Mappings, wantMappings)
+	}
+}
+
+func TestParseConfigInvalidParameters(t *testing.T) {
+	input := template.Input{
+		TemplatePath:   "testdata/constraints.yaml",
+		ParameterPaths: []string{"testdata/constraints-values.json"},
+	}
+
+	_, err := template.Read(input)
+
+	var paramErr *template.ParameterError
+	if !errors.As(err, &paramErr) {
+		t.Fatalf("Got %v but expected a ParameterError", err)
+	}
+
+	got := []string{}
+	for _, problem := range paramErr.Problems {
+		got = append(got, problem.String())
+	}
+
+	want := []string{
+		`testdata/constraints-values.json:2:18: error: parameter Environment: value "qa" is not one of the allowed values [dev staging production]`,
+		`testdata/constraints-values.json:3:17: error: parameter BucketName: value "Invalid_Bucket" does not match the allowed pattern [a-z0-9-]+`,
+		`testdata/constraints-values.json:4:20: error: parameter InstanceCount: value 12 is greater than the maximum value of 10`,
+		`testdata/constraints-values.json:5:12: error: parameter Ports: value "http" is not a number`,
+		`testdata/constraints-values.json:6:12: error: parameter Extra: value is shorter than the minimum length of 3`,
+		`testdata/constraints-values.json:7:14: error: parameter Unknown is not declared in the template`,
+		`testdata/constraints.yaml:18:3: error: parameter Required has no default and is not set`,
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Got %#v but expected %#v", got, want)
+	}
+}
+
+func TestParseConfigTypedValues(t *testing.T) {
+	type test struct {
+		name   string
+		policy template.NestedPolicy
+		want   map[string]string
+		errs   []string
+	}
+	tests := []test{
+		{
+			name:   "JSON encodes nested objects",
+			policy: template.NestedJSON,
+			want: map[string]string{
+				"Count":   "3",
+				"Ratio":   "1.5",
+				"Enabled": "true",
+				"Zones":   "us-west-2a,us-west-2b",
+				"Ports":   "80,443",
+				"Config":  `{"size":10,"tags":["a","b"]}`,
+			},
+		},
+		{
+			name:   "Flattens nested objects",
+			policy: template.NestedFlatten,
+			errs: []string{
+				`testdata/typed-values.json:8:34: error: parameter ConfigTags (from Config.tags) is not declared in the template`,
+			},
+		},
+		{
+			name:   "Rejects nested objects",
+			policy: template.NestedReject,
+			errs: []string{
+				`testdata/typed-values.json:8:13: error: parameter Config: nested objects are not allowed, use --nested-parameters flatten or json`,
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := template.Read(template.Input{
+				TemplatePath:   "testdata/typed.yaml",
+				ParameterPaths: []string{"testdata/typed-values.json"},
+				NestedPolicy:   tc.policy,
+			})
+
+			if len(tc.errs) > 0 {
+				var paramErr *template.ParameterError
+				if !errors.As(err, &paramErr) {
+					t.Fatalf("Got %v but expected a ParameterError", err)
+				}
+
+				gotErrs := []string{}
+				for _, problem := range paramErr.Problems {
+					gotErrs = append(gotErrs, problem.String())
+				}
+
+				if !reflect.DeepEqual(gotErrs, tc.errs) {
+					t.Fatalf("Got %#v but expected %#v", gotErrs, tc.errs)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			gotParameters := map[string]string{}
+			for _, parameter := range got.Parameters {
+				gotParameters[*parameter.ParameterKey] = *parameter.ParameterValue
+			}
+
+			if !reflect.DeepEqual(gotParameters, tc.want) {
+				t.Fatalf("Got %v but expected %v", gotParameters, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseConfigMappings(t *testing.T) {
+	type test struct {
+		name        string
+		mapping     template.MappingStrategy
+		mappingPath string
+		want        map[string]string
+		errs        []string
+	}
+	tests := []test{
+		{
+			name:        "Selects values with JSONPath",
+			mapping:     template.MappingJSONPath,
+			mappingPath: "testdata/mapping-jsonpath.yaml",
+			want: map[string]string{
+				"BucketName": "md-test-cf-1234",
+				"RoleArn":    "arn:aws:iam::123456789012:role/deploy",
+				"SubnetIds":  "subnet-1,subnet-2",
+			},
+		},
+		{
+			name:        "Renames keys with a table",
+			mapping:     template.MappingTable,
+			mappingPath: "testdata/mapping-table.yaml",
+			errs: []string{
+				`testdata/connections-values.json:11:20: error: parameter connections.vpc.data.subnets is not declared in the template`,
+				`testdata/connections.yaml:7:3: error: parameter SubnetIds has no default and is not set`,
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := template.Read(template.Input{
+				TemplatePath:   "testdata/connections.yaml",
+				ParameterPaths: []string{"testdata/connections-values.json"},
+				Mapping:        tc.mapping,
+				MappingPath:    tc.mappingPath,
+			})
+
+			if len(tc.errs) > 0 {
+				var paramErr *template.ParameterError
+				if !errors.As(err, &paramErr) {
+					t.Fatalf("Got %v but expected a ParameterError", err)
+				}
+
+				gotErrs := []string{}
+				for _, problem := range paramErr.Problems {
+					gotErrs = append(gotErrs, problem.String())
+				}
+
+				if !reflect.DeepEqual(gotErrs, tc.errs) {
+					t.Fatalf("Got %#v but expected %#v", gotErrs, tc.errs)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			gotParameters := map[string]string{}
+			for _, parameter := range got.Parameters {
+				gotParameters[*parameter.ParameterKey] = *parameter.ParameterValue
+			}
+
+			if !reflect.DeepEqual(gotParameters, tc.want) {
+				t.Fatalf("Got %v but expected %v", gotParameters, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseConfigFormats(t *testing.T) {
+	type test struct {
+		name        string
+		path        string
+		want        []types.Parameter
+		tags        map[string]string
+		stackPolicy string
+	}
+	tests := []test{
+		{
+			name: "Reads the AWS CLI format",
+			path: "testdata/s3-values-cli.json",
+			want: []types.Parameter{
+				{ParameterKey: aws.String("BucketName"), ParameterValue: aws.String("md-test-cf-1234")},
+				{ParameterKey: aws.String("ConnectionsAuth"), ParameterValue: aws.String("test")},
+				{ParameterKey: aws.String("DevBucketName"), UsePreviousValue: aws.Bool(true)},
+			},
+		},
+		{
+			name: "Reads Key=Value strings",
+			path: "testdata/s3-values-deploy.json",
+			want: []types.Parameter{
+				{ParameterKey: aws.String("BucketName"), ParameterValue: aws.String("md-test-cf-1234")},
+				{ParameterKey: aws.String("DevBucketName"), ParameterValue: aws.String("md-test-dev-cf-1234")},
+				{ParameterKey: aws.String("ConnectionsAuth"), ParameterValue: aws.String("test")},
+			},
+		},
+		{
+			name: "Reads a CodePipeline template configuration",
+			path: "testdata/s3-values-pipeline.json",
+			want: []types.Parameter{
+				{ParameterKey: aws.String("BucketName"), ParameterValue: aws.String("md-test-cf-1234")},
+				{ParameterKey: aws.String("DevBucketName"), ParameterValue: aws.String("md-test-dev-cf-1234")},
+				{ParameterKey: aws.String("ConnectionsAuth"), ParameterValue: aws.String("test")},
+			},
+			tags:        map[string]string{"team": "platform"},
+			stackPolicy: `{"Statement":[{"Action":"Update:Replace","Effect":"Deny","Principal":"*","Resource":"LogicalResourceId/MainBucket"}]}`,
+		},
+		{
+			name: "Reads YAML",
+			path: "testdata/s3-values.yaml",
+			want: []types.Parameter{
+				{ParameterKey: aws.String("BucketName"), ParameterValue: aws.String("md-test-cf-1234")},
+				{ParameterKey: aws.String("DevBucketName"), ParameterValue: aws.String("md-test-dev-cf-1234")},
+				{ParameterKey: aws.String("ConnectionsAuth"), ParameterValue: aws.String("test")},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := template.Read(template.Input{
+				TemplatePath:   "testdata/s3.yaml",
+				ParameterPaths: []string{tc.path},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got.Parameters, tc.want) {
+				t.Fatalf("Got %v but expected %v", got.Parameters, tc.want)
+			}
+
+			if !reflect.DeepEqual(got.Tags, tc.tags) {
+				t.Fatalf("Got %v but expected %v", got.Tags, tc.tags)
+			}
+
+			if string(got.StackPolicy) != tc.stackPolicy {
+				t.Fatalf("Got %s but expected %s", got.StackPolicy, tc.stackPolicy)
+			}
+		})
+	}
+}
+
+func TestParseConfigLayers(t *testing.T) {
+	got, err := template.Read(template.Input{
+		TemplatePath:   "testdata/s3.yaml",
+		ParameterPaths: []string{"testdata/s3-values.json", "testdata/s3-values-overlay.yaml"},
+		Environment:    []string{"HOME=/root", "FOGMACHINE_PARAM_BucketName=from-env"},
+		Overrides:      []string{"connections.auth=from-flag"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []template.EffectiveParameter{
+		{Name: "BucketName", Value: "from-env", Source: "FOGMACHINE_PARAM_BucketName"},
+		{Name: "DevBucketName", Value: "md-test-dev-overlay", Source: "testdata/s3-values-overlay.yaml:1:16"},
+		{Name: "ConnectionsAuth", Value: template.Redacted, Source: "--parameter connections.auth"},
+		{Name: "ConnectionsRegion", Value: "us-east-1", Source: "testdata/s3-values-overlay.yaml:3:11"},
+	}
+
+	if !reflect.DeepEqual(got.EffectiveParameters(), want) {
+		t.Fatalf("Got %v but expected %v", got.EffectiveParameters(), want)
+	}
+
+	for _, parameter := range got.Parameters {
+		if *parameter.ParameterKey == "ConnectionsAuth" && *parameter.ParameterValue != "from-flag" {
+			t.Fatalf("Got %s but expected from-flag", *parameter.ParameterValue)
+		}
+	}
+
+	if !reflect.DeepEqual(got.Secrets, []string{"from-flag"}) {
+		t.Fatalf("Got %v but expected [from-flag]", got.Secrets)
+	}
+}
+
+func TestParseConfigPreviousValues(t *testing.T) {
+	got, err := template.Read(template.Input{
+		TemplatePath:       "testdata/s3.yaml",
+		Overrides:          []string{"BucketName=md-test-cf-1234"},
+		ExistingParameters: []string{"BucketName", "DevBucketName", "ConnectionsAuth", "ConnectionsRegion"},
+		Previous: template.PreviousValues{
+			Omitted: true,
+			Reset:   []string{"ConnectionsRegion"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []types.Parameter{
+		{ParameterKey: aws.String("BucketName"), ParameterValue: aws.String("md-test-cf-1234")},
+		{ParameterKey: aws.String("DevBucketName"), UsePreviousValue: aws.Bool(true)},
+		{ParameterKey: aws.String("ConnectionsAuth"), UsePreviousValue: aws.Bool(true)},
+	}
+
+	if !reflect.DeepEqual(got.Parameters, want) {
+		t.Fatalf("Got %v but expected %v", got.Parameters, want)
+	}
 }
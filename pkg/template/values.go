@@ -0,0 +1,301 @@
+package template
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// NestedPolicy controls how objects in the parameter file become parameters
+type NestedPolicy string
+
+const (
+	// NestedFlatten joins the keys of nested objects with a dot, {"a": {"b": 1}} sets a.b
+	NestedFlatten NestedPolicy = "flatten"
+	// NestedJSON encodes nested objects as JSON into a single parameter
+	NestedJSON NestedPolicy = "json"
+	// NestedReject reports nested objects as errors
+	NestedReject NestedPolicy = "reject"
+)
+
+// ParseNestedPolicy parses the --nested-parameters flag, an empty value is NestedFlatten
+func ParseNestedPolicy(policy string) (NestedPolicy, error) {
+	switch p := NestedPolicy(policy); p {
+	case "", NestedFlatten:
+		return NestedFlatten, nil
+	case NestedJSON, NestedReject:
+		return p, nil
+	}
+
+	return "", fmt.Errorf("unknown nested parameter policy %q, expected one of flatten, json or reject", policy)
+}
+
+// valueReader converts the values in the parameter files to the strings CloudFormation accepts
+type valueReader struct {
+	definitions map[string]*ParameterDefinition
+	policy      NestedPolicy
+	strategy    MappingStrategy
+	mappingPath string
+	entries     []mappingEntry
+	table       map[string]string
+	// origins is the file, environment variable or flag each node was read from
+	origins map[*yaml.Node]string
+	// previous and pending are the parameters set with UsePreviousValue, overall and in the current file
+	previous    map[string]*yaml.Node
+	pending     map[string]*yaml.Node
+	values      []parameterValue
+	problems    []Problem
+	tags        map[string]string
+	stackPolicy []byte
+}
+
+func newValueReader(input Input, definitions map[string]*ParameterDefinition) (*valueReader, error) {
+	reader := &valueReader{
+		definitions: definitions,
+		policy:      input.NestedPolicy,
+		strategy:    input.Mapping,
+		mappingPath: input.MappingPath,
+		origins:     make(map[*yaml.Node]string),
+		previous:    make(map[string]*yaml.Node),
+		values:      []parameterValue{},
+	}
+
+	if reader.policy == "" {
+		reader.policy = NestedFlatten
+	}
+
+	if reader.strategy == "" {
+		reader.strategy = MappingPascal
+	}
+
+	if reader.strategy != MappingPascal {
+		if input.MappingPath == "" {
+			return nil, fmt.Errorf("the %s parameter mapping requires a mapping file", reader.strategy)
+		}
+
+		var err error
+		if reader.entries, err = readMappingFile(input.MappingPath); err != nil {
+			return nil, err
+		}
+	}
+
+	reader.table = make(map[string]string, len(reader.entries))
+	for _, entry := range reader.entries {
+		reader.table[entry.Key] = entry.Value
+	}
+
+	return reader, nil
+}
+
+// addParameterMap adds a map of parameter names to values, mapping the nested values to parameters
+func (r *valueReader) addParameterMap(node *yaml.Node) error {
+	if r.strategy == MappingJSONPath {
+		return r.addSelected(node)
+	}
+
+	r.addMapping("", node)
+
+	return nil
+}
+
+func (r *valueReader) addMapping(prefix string, node *yaml.Node) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		source := prefix + node.Content[i].Value
+		r.add(r.parameterName(source), source, node.Content[i+1])
+	}
+}
+
+// addSelected sets the top level values that aren't objects, then the parameters the jsonpath mapping selects.
+// Objects are only read through the selectors.
+func (r *valueReader) addSelected(root *yaml.Node) error {
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if value := root.Content[i+1]; value.Kind != yaml.MappingNode {
+			r.add(root.Content[i].Value, root.Content[i].Value, value)
+		}
+	}
+
+	for _, entry := range r.entries {
+		steps, err := parseSelector(entry.Value)
+		if err != nil {
+			return fmt.Errorf("%s:%d:%d: %w", r.mappingPath, entry.Node.Line, entry.Node.Column, err)
+		}
+
+		node, ok := selectNodes(root, steps)
+		if !ok {
+			r.problems = append(r.problems, problemAt(r.mappingPath, entry.Node, "parameter %s: selector %s doesn't match anything in the parameters", entry.Key, entry.Value))
+			continue
+		}
+
+		r.add(entry.Key, entry.Value, node)
+	}
+
+	return nil
+}
+
+// parameterName maps a flattened key to the template parameter it sets, top level keys are used as is
+func (r *valueReader) parameterName(source string) string {
+	if !strings.Contains(source, ".") {
+		return source
+	}
+
+	switch r.strategy {
+	case MappingPascal:
+		return pascalCase(source)
+	case MappingTable:
+		if name, ok := r.table[source]; ok {
+			return name
+		}
+	case MappingJSONPath:
+	}
+
+	return source
+}
+
+func (r *valueReader) add(key, source string, node *yaml.Node) {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+
+	value := parameterValue{Key: key, Source: source, Path: r.origin(node), Node: node}
+
+	switch node.Kind {
+	case yaml.ScalarNode:
+		r.addScalar(value)
+	case yaml.SequenceNode:
+		r.addSequence(value)
+	case yaml.MappingNode:
+		r.addObject(value)
+	case yaml.DocumentNode, yaml.AliasNode:
+	}
+}
+
+// addScalar adds a string, number or boolean. Null leaves the parameter unset so the template default is used.
+func (r *valueReader) addScalar(value parameterValue) {
+	if value.Node.ShortTag() == "!!null" {
+		return
+	}
+
+	str, err := scalarString(value.Node)
+	if err != nil {
+		r.problems = append(r.problems, value.problem("parameter %s: %s", value.label(), err))
+		return
+	}
+
+	value.Value = str
+	r.values = append(r.values, value)
+}
+
+// addSequence joins arrays for list parameters. Other parameters only take an array when it's JSON encoded.
+func (r *valueReader) addSequence(value parameterValue) {
+	definition, ok := r.definitions[value.Key]
+	switch {
+	case ok && definition.IsList():
+		r.addList(value)
+	case r.policy == NestedJSON:
+		r.addJSON(value)
+	case ok:
+		r.problems = append(r.problems, value.problem("parameter %s: an array can only be set for a list parameter, not %s", value.label(), definition.Type))
+	default:
+		r.problems = append(r.problems, value.problem("parameter %s is not declared in the template", value.label()))
+	}
+}
+
+func (r *valueReader) addList(value parameterValue) {
+	items := make([]string, 0, len(value.Node.Content))
+	for _, item := range value.Node.Content {
+		if item.Kind == yaml.AliasNode {
+			item = item.Alias
+		}
+
+		if item.Kind != yaml.ScalarNode || item.ShortTag() == "!!null" {
+			r.problem(item, "parameter %s: list items must be strings, numbers or booleans", value.label())
+			return
+		}
+
+		str, err := scalarString(item)
+		if err != nil {
+			r.problem(item, "parameter %s: %s", value.label(), err)
+			return
+		}
+
+		// CloudFormation splits list parameters on commas so an item can't contain one
+		if strings.Contains(str, ",") {
+			r.problem(item, "parameter %s: list item %q contains a comma", value.label(), str)
+			return
+		}
+
+		items = append(items, str)
+	}
+
+	value.Value = strings.Join(items, ",")
+	r.values = append(r.values, value)
+}
+
+func (r *valueReader) addObject(value parameterValue) {
+	switch r.policy {
+	case NestedFlatten:
+		r.addMapping(value.Source+".", value.Node)
+	case NestedJSON:
+		r.addJSON(value)
+	case NestedReject:
+		r.problems = append(r.problems, value.problem("parameter %s: nested objects are not allowed, use --nested-parameters flatten or json", value.label()))
+	}
+}
+
+func (r *valueReader) addJSON(value parameterValue) {
+	var decoded interface{}
+	if err := value.Node.Decode(&decoded); err != nil {
+		r.problems = append(r.problems, value.problem("parameter %s: %s", value.label(), err))
+		return
+	}
+
+	buf := &bytes.Buffer{}
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+
+	if err := encoder.Encode(decoded); err != nil {
+		r.problems = append(r.problems, value.problem("parameter %s: unable to encode as JSON: %s", value.label(), err))
+		return
+	}
+
+	value.Value = strings.TrimSuffix(buf.String(), "\n")
+	r.values = append(r.values, value)
+}
+
+func (r *valueReader) problem(node *yaml.Node, format string, args ...interface{}) {
+	r.problems = append(r.problems, problemAt(r.origin(node), node, format, args...))
+}
+
+// scalarString formats numbers and booleans canonically so 1.50 is sent as 1.5 and True as true
+func scalarString(node *yaml.Node) (string, error) {
+	var value interface{}
+	if err := node.Decode(&value); err != nil {
+		return "", err
+	}
+
+	switch v := value.(type) {
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case uint64:
+		return strconv.FormatUint(v, 10), nil
+	case float64:
+		if math.IsInf(v, 0) || math.IsNaN(v) {
+			return "", fmt.Errorf("value %s is not a finite number", node.Value)
+		}
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case string:
+		return v, nil
+	}
+
+	// Timestamps and binary values are sent as written
+	return node.Value, nil
+}
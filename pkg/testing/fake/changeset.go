@@ -0,0 +1,277 @@
+package fake
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+)
+
+// NoChangesReason is the status reason CloudFormation fails a changeset with when it wouldn't change anything
+const NoChangesReason = "The submitted information didn't contain changes. Submit different information to create a change set."
+
+type changeSet struct {
+	id              string
+	name            string
+	description     string
+	stackID         string
+	stackName       string
+	changeSetType   types.ChangeSetType
+	status          types.ChangeSetStatus
+	executionStatus types.ExecutionStatus
+	reason          string
+	changes         []types.Change
+	resources       map[string]resource
+	parameters      []types.Parameter
+	tags            []types.Tag
+	created         time.Time
+	// seq orders changesets created at the same time
+	seq int
+	// failure is the reason the changeset fails once it's created, empty when it succeeds
+	failure string
+}
+
+// CreateChangeSet validates the request and starts creating the changeset. A changeset that creates a stack
+// creates it in REVIEW_IN_PROGRESS.
+func (f *CloudFormation) CreateChangeSet(_ context.Context, params *cloudformation.CreateChangeSetInput, _ ...func(*cloudformation.Options)) (*cloudformation.CreateChangeSetOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls["CreateChangeSet"]++
+
+	parsed, err := parseTemplate(aws.ToString(params.TemplateBody))
+	if err != nil {
+		return nil, err
+	}
+
+	if missing := missingCapabilities(parsed.capabilities, params.Capabilities); missing != "" {
+		return nil, apiError("InsufficientCapabilitiesException", "Requires capabilities : [%s]", missing)
+	}
+
+	name := aws.ToString(params.StackName)
+	s := f.stacks[name]
+
+	switch params.ChangeSetType {
+	case types.ChangeSetTypeCreate:
+		if s != nil && s.status != types.StackStatusReviewInProgress {
+			return nil, apiError("AlreadyExistsException", "Stack [%s] already exists and cannot be created again with the changeSet [%s].", name, aws.ToString(params.ChangeSetName))
+		}
+		if s == nil {
+			s = f.newStack(name)
+			f.record(s, s.stackStep(types.StackStatusReviewInProgress, "User Initiated", ""))
+		}
+	default:
+		if s == nil || s.status == types.StackStatusReviewInProgress {
+			return nil, apiError("ValidationError", "Stack [%s] does not exist", name)
+		}
+		if !updatable(s.status) {
+			return nil, apiError("ValidationError", "Stack:%s is in %s state and can not be updated.", s.id, s.status)
+		}
+	}
+
+	parameters := resolveParameters(params.Parameters, s.parameters)
+
+	f.ids++
+	cs := &changeSet{
+		id:              fmt.Sprintf("arn:aws:cloudformation:us-west-2:123456789012:changeSet/%s/%08d-fake", aws.ToString(params.ChangeSetName), f.ids),
+		name:            aws.ToString(params.ChangeSetName),
+		description:     aws.ToString(params.Description),
+		stackID:         s.id,
+		stackName:       s.name,
+		changeSetType:   params.ChangeSetType,
+		status:          types.ChangeSetStatusCreatePending,
+		executionStatus: types.ExecutionStatusUnavailable,
+		changes:         diffResources(s.resources, parsed.resources, changedParameters(s.parameters, parameters)),
+		resources:       parsed.resources,
+		parameters:      parameters,
+		tags:            params.Tags,
+		created:         f.Now(),
+		seq:             f.ids,
+	}
+
+	switch {
+	case f.changeSetFailure != "":
+		cs.failure, f.changeSetFailure = f.changeSetFailure, ""
+	case len(cs.changes) == 0:
+		cs.failure = NoChangesReason
+	}
+
+	f.changeSets[cs.id] = cs
+
+	return &cloudformation.CreateChangeSetOutput{Id: aws.String(cs.id), StackId: aws.String(s.id)}, nil
+}
+
+// DescribeChangeSet advances the changeset from CREATE_PENDING through CREATE_IN_PROGRESS to CREATE_COMPLETE, or
+// FAILED, a status per call and describes it
+func (f *CloudFormation) DescribeChangeSet(_ context.Context, params *cloudformation.DescribeChangeSetInput, _ ...func(*cloudformation.Options)) (*cloudformation.DescribeChangeSetOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls["DescribeChangeSet"]++
+
+	cs := f.findChangeSet(aws.ToString(params.ChangeSetName), aws.ToString(params.StackName))
+	if cs == nil {
+		return nil, apiError("ChangeSetNotFound", "ChangeSet [%s] does not exist", aws.ToString(params.ChangeSetName))
+	}
+
+	switch {
+	case cs.status == types.ChangeSetStatusCreatePending:
+		cs.status = types.ChangeSetStatusCreateInProgress
+	case cs.status == types.ChangeSetStatusCreateInProgress && cs.failure != "":
+		cs.status, cs.reason = types.ChangeSetStatusFailed, cs.failure
+	case cs.status == types.ChangeSetStatusCreateInProgress:
+		cs.status, cs.executionStatus = types.ChangeSetStatusCreateComplete, types.ExecutionStatusAvailable
+	}
+
+	output := &cloudformation.DescribeChangeSetOutput{
+		ChangeSetId:     aws.String(cs.id),
+		ChangeSetName:   aws.String(cs.name),
+		Description:     aws.String(cs.description),
+		StackId:         aws.String(cs.stackID),
+		StackName:       aws.String(cs.stackName),
+		Status:          cs.status,
+		ExecutionStatus: cs.executionStatus,
+		Parameters:      cs.parameters,
+		Tags:            cs.tags,
+		CreationTime:    aws.Time(cs.created),
+	}
+	if cs.reason != "" {
+		output.StatusReason = aws.String(cs.reason)
+	}
+	if cs.status != types.ChangeSetStatusCreatePending && cs.status != types.ChangeSetStatusCreateInProgress {
+		output.Changes = cs.changes
+	}
+
+	return output, nil
+}
+
+// ExecuteChangeSet starts the stack operation, the changeset must be CREATE_COMPLETE and AVAILABLE. The other
+// changesets of the stack become OBSOLETE.
+func (f *CloudFormation) ExecuteChangeSet(_ context.Context, params *cloudformation.ExecuteChangeSetInput, _ ...func(*cloudformation.Options)) (*cloudformation.ExecuteChangeSetOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls["ExecuteChangeSet"]++
+
+	cs := f.findChangeSet(aws.ToString(params.ChangeSetName), aws.ToString(params.StackName))
+	if cs == nil {
+		return nil, apiError("ChangeSetNotFound", "ChangeSet [%s] does not exist", aws.ToString(params.ChangeSetName))
+	}
+
+	if cs.status != types.ChangeSetStatusCreateComplete || cs.executionStatus != types.ExecutionStatusAvailable {
+		return nil, apiError("InvalidChangeSetStatus", "ChangeSet [%s] cannot be executed in its current status of [%s]", cs.id, cs.status)
+	}
+
+	s := f.byID[cs.stackID]
+	if inProgress(s.status) {
+		return nil, apiError("ValidationError", "Stack:%s is in %s state and can not be updated.", s.id, s.status)
+	}
+
+	for _, other := range f.changeSets {
+		if other.stackID == cs.stackID && other != cs {
+			other.executionStatus = types.ExecutionStatusObsolete
+		}
+	}
+
+	cs.executionStatus = types.ExecutionStatusExecuteInProgress
+	s.steps = f.executeSteps(s, cs, aws.ToString(params.ClientRequestToken))
+
+	return &cloudformation.ExecuteChangeSetOutput{}, nil
+}
+
+// ListChangeSets summarizes the changesets of the stack, oldest first, without advancing them
+func (f *CloudFormation) ListChangeSets(_ context.Context, params *cloudformation.ListChangeSetsInput, _ ...func(*cloudformation.Options)) (*cloudformation.ListChangeSetsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls["ListChangeSets"]++
+
+	s := f.lookup(aws.ToString(params.StackName))
+	if s == nil {
+		return nil, notExist(aws.ToString(params.StackName))
+	}
+
+	changeSets := []*changeSet{}
+	for _, cs := range f.changeSets {
+		if cs.stackID == s.id {
+			changeSets = append(changeSets, cs)
+		}
+	}
+	sort.Slice(changeSets, func(i, j int) bool { return changeSets[i].seq < changeSets[j].seq })
+
+	output := &cloudformation.ListChangeSetsOutput{Summaries: make([]types.ChangeSetSummary, 0, len(changeSets))}
+	for _, cs := range changeSets {
+		summary := types.ChangeSetSummary{
+			ChangeSetId:     aws.String(cs.id),
+			ChangeSetName:   aws.String(cs.name),
+			Description:     aws.String(cs.description),
+			StackId:         aws.String(cs.stackID),
+			StackName:       aws.String(cs.stackName),
+			Status:          cs.status,
+			ExecutionStatus: cs.executionStatus,
+			CreationTime:    aws.Time(cs.created),
+		}
+		if cs.reason != "" {
+			summary.StatusReason = aws.String(cs.reason)
+		}
+		output.Summaries = append(output.Summaries, summary)
+	}
+
+	return output, nil
+}
+
+// DeleteChangeSet deletes the changeset, a changeset that's executing or executed can't be deleted
+func (f *CloudFormation) DeleteChangeSet(_ context.Context, params *cloudformation.DeleteChangeSetInput, _ ...func(*cloudformation.Options)) (*cloudformation.DeleteChangeSetOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls["DeleteChangeSet"]++
+
+	cs := f.findChangeSet(aws.ToString(params.ChangeSetName), aws.ToString(params.StackName))
+	if cs == nil {
+		return nil, apiError("ChangeSetNotFound", "ChangeSet [%s] does not exist", aws.ToString(params.ChangeSetName))
+	}
+
+	if cs.executionStatus == types.ExecutionStatusExecuteInProgress || cs.executionStatus == types.ExecutionStatusExecuteComplete {
+		return nil, apiError("InvalidChangeSetStatus", "ChangeSet [%s] cannot be deleted in its current status of [%s]", cs.id, cs.executionStatus)
+	}
+
+	delete(f.changeSets, cs.id)
+
+	return &cloudformation.DeleteChangeSetOutput{}, nil
+}
+
+// findChangeSet finds a changeset by ID, or by name within the stack
+func (f *CloudFormation) findChangeSet(nameOrID, stackName string) *changeSet {
+	if cs, ok := f.changeSets[nameOrID]; ok {
+		return cs
+	}
+
+	s := f.lookup(stackName)
+	if s == nil {
+		return nil
+	}
+
+	for _, cs := range f.changeSets {
+		if cs.name == nameOrID && cs.stackID == s.id {
+			return cs
+		}
+	}
+	return nil
+}
+
+// resolveParameters replaces UsePreviousValue with the value the stack has
+func resolveParameters(parameters, previous []types.Parameter) []types.Parameter {
+	old := map[string]*string{}
+	for _, parameter := range previous {
+		old[aws.ToString(parameter.ParameterKey)] = parameter.ParameterValue
+	}
+
+	resolved := make([]types.Parameter, 0, len(parameters))
+	for _, parameter := range parameters {
+		if aws.ToBool(parameter.UsePreviousValue) {
+			parameter = types.Parameter{ParameterKey: parameter.ParameterKey, ParameterValue: old[aws.ToString(parameter.ParameterKey)]}
+		}
+		resolved = append(resolved, parameter)
+	}
+	return resolved
+}
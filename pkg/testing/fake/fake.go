@@ -0,0 +1,412 @@
+// Package fake is an in-memory CloudFormation for tests. It models stacks, changesets and the status transitions
+// between them, including failures and rollbacks. An operation advances one stack event each time DescribeStacks
+// is called for its stack, so a client polling the stack sees it progress the same way on every run.
+package fake
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/aws/smithy-go"
+)
+
+// CloudFormation implements client.CloudFormationAPI in memory. It's safe for concurrent use.
+type CloudFormation struct {
+	mu sync.Mutex
+
+	// Now stamps events and changesets, time.Now by default
+	Now func() time.Time
+	// EventsPageSize is the most events DescribeStackEvents returns per page, 100 by default like CloudFormation
+	EventsPageSize int
+
+	// stacks holds live stacks by name, byID every stack including deleted ones
+	stacks     map[string]*stack
+	byID       map[string]*stack
+	changeSets map[string]*changeSet
+	outputs    map[string][]types.Output
+
+	failures     map[string]string
+	failRollback bool
+	// cleanupFailures are the resources whose cleanup fails after the next continued rollback
+	cleanupFailures map[string]string
+	// changeSetFailure is the reason the next changeset fails with
+	changeSetFailure string
+
+	calls map[string]int
+	ids   int
+}
+
+// New returns a CloudFormation without any stacks
+func New() *CloudFormation {
+	return &CloudFormation{
+		Now:             time.Now,
+		EventsPageSize:  100,
+		stacks:          map[string]*stack{},
+		byID:            map[string]*stack{},
+		changeSets:      map[string]*changeSet{},
+		outputs:         map[string][]types.Output{},
+		failures:        map[string]string{},
+		cleanupFailures: map[string]string{},
+		calls:           map[string]int{},
+	}
+}
+
+// FailResource makes the next create, update or delete of the resource fail with reason
+func (f *CloudFormation) FailResource(logicalID, reason string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures[logicalID] = reason
+}
+
+// FailRollback makes the next rollback fail too, leaving the stack in ROLLBACK_FAILED or UPDATE_ROLLBACK_FAILED
+func (f *CloudFormation) FailRollback() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failRollback = true
+}
+
+// FailCleanup makes the cleanup after the next continued rollback report DELETE_FAILED for the resource. The
+// rollback still completes, like it does in CloudFormation.
+func (f *CloudFormation) FailCleanup(logicalID, reason string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.cleanupFailures[logicalID] = reason
+}
+
+// FailChangeSet makes the next changeset fail with reason once it's created
+func (f *CloudFormation) FailChangeSet(reason string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.changeSetFailure = reason
+}
+
+// SetOutputs sets the outputs the stack reports once its next operation succeeds
+func (f *CloudFormation) SetOutputs(stackName string, outputs map[string]string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	keys := make([]string, 0, len(outputs))
+	for key := range outputs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	f.outputs[stackName] = make([]types.Output, 0, len(keys))
+	for _, key := range keys {
+		f.outputs[stackName] = append(f.outputs[stackName], types.Output{OutputKey: aws.String(key), OutputValue: aws.String(outputs[key])})
+	}
+}
+
+// AddStack creates a stack with the resources of the template directly in status, for tests that start from a
+// stack in a particular state. The stack has no events.
+func (f *CloudFormation) AddStack(name string, status types.StackStatus, templateBody string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	parsed, err := parseTemplate(templateBody)
+	if err != nil {
+		return err
+	}
+
+	s := f.newStack(name)
+	s.status = status
+	for logicalID, res := range parsed.resources {
+		res.PhysicalID = f.physicalID(name, logicalID)
+		s.resources[logicalID] = res
+	}
+
+	return nil
+}
+
+// Stack returns the stack as DescribeStacks would without advancing it
+func (f *CloudFormation) Stack(name string) (types.Stack, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	s := f.lookup(name)
+	if s == nil {
+		return types.Stack{}, false
+	}
+
+	return s.describe(), true
+}
+
+// Events returns the events of the stack, oldest first
+func (f *CloudFormation) Events(name string) []types.StackEvent {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	s := f.lookup(name)
+	if s == nil {
+		return nil
+	}
+
+	return append([]types.StackEvent{}, s.events...)
+}
+
+// Calls returns how many times the API operation was called
+func (f *CloudFormation) Calls(operation string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[operation]
+}
+
+// DescribeStacks advances the stack's operation by a step and describes it
+func (f *CloudFormation) DescribeStacks(_ context.Context, params *cloudformation.DescribeStacksInput, _ ...func(*cloudformation.Options)) (*cloudformation.DescribeStacksOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls["DescribeStacks"]++
+
+	name := aws.ToString(params.StackName)
+	if name == "" {
+		names := make([]string, 0, len(f.stacks))
+		for stackName := range f.stacks {
+			names = append(names, stackName)
+		}
+		sort.Strings(names)
+
+		output := &cloudformation.DescribeStacksOutput{}
+		for _, stackName := range names {
+			output.Stacks = append(output.Stacks, f.stacks[stackName].describe())
+		}
+		return output, nil
+	}
+
+	s := f.lookup(name)
+	if s == nil {
+		return nil, notExist(name)
+	}
+
+	f.advance(s)
+
+	// A deleted stack can only be described by its ID
+	if f.lookup(name) == nil {
+		return nil, notExist(name)
+	}
+
+	return &cloudformation.DescribeStacksOutput{Stacks: []types.Stack{s.describe()}}, nil
+}
+
+// DescribeStackEvents returns the events of the stack so far, newest first, EventsPageSize at a time
+func (f *CloudFormation) DescribeStackEvents(_ context.Context, params *cloudformation.DescribeStackEventsInput, _ ...func(*cloudformation.Options)) (*cloudformation.DescribeStackEventsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls["DescribeStackEvents"]++
+
+	s := f.lookup(aws.ToString(params.StackName))
+	if s == nil {
+		return nil, notExist(aws.ToString(params.StackName))
+	}
+
+	// The token is how many of the oldest events are left, so new events don't shift the pages
+	end := len(s.events)
+	if params.NextToken != nil {
+		var err error
+		if end, err = strconv.Atoi(aws.ToString(params.NextToken)); err != nil || end < 0 || end > len(s.events) {
+			return nil, apiError("ValidationError", "Invalid NextToken %s", aws.ToString(params.NextToken))
+		}
+	}
+
+	start := 0
+	if f.EventsPageSize > 0 && end > f.EventsPageSize {
+		start = end - f.EventsPageSize
+	}
+
+	output := &cloudformation.DescribeStackEventsOutput{StackEvents: make([]types.StackEvent, 0, end-start)}
+	for i := end - 1; i >= start; i-- {
+		output.StackEvents = append(output.StackEvents, s.events[i])
+	}
+	if start > 0 {
+		output.NextToken = aws.String(strconv.Itoa(start))
+	}
+
+	return output, nil
+}
+
+// DeleteStack starts deleting the stack, deleting a stack that doesn't exist succeeds like it does in AWS
+func (f *CloudFormation) DeleteStack(_ context.Context, params *cloudformation.DeleteStackInput, _ ...func(*cloudformation.Options)) (*cloudformation.DeleteStackOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls["DeleteStack"]++
+
+	s := f.lookup(aws.ToString(params.StackName))
+	if s == nil || s.status == types.StackStatusDeleteComplete {
+		return &cloudformation.DeleteStackOutput{}, nil
+	}
+
+	if inProgress(s.status) {
+		return nil, apiError("ValidationError", "Stack [%s] cannot be deleted while in status %s", s.name, s.status)
+	}
+
+	s.steps = f.deleteSteps(s, aws.ToString(params.ClientRequestToken))
+
+	return &cloudformation.DeleteStackOutput{}, nil
+}
+
+// ContinueUpdateRollback continues the rollback of a stack in UPDATE_ROLLBACK_FAILED. A resource set to fail
+// fails the rollback again unless it's skipped.
+func (f *CloudFormation) ContinueUpdateRollback(_ context.Context, params *cloudformation.ContinueUpdateRollbackInput, _ ...func(*cloudformation.Options)) (*cloudformation.ContinueUpdateRollbackOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls["ContinueUpdateRollback"]++
+
+	s := f.lookup(aws.ToString(params.StackName))
+	if s == nil {
+		return nil, notExist(aws.ToString(params.StackName))
+	}
+
+	if s.status != types.StackStatusUpdateRollbackFailed {
+		return nil, apiError("ValidationError", "Stack:%s is in %s state and can not be updated.", s.id, s.status)
+	}
+
+	for _, id := range params.ResourcesToSkip {
+		if _, ok := s.resources[id]; !ok {
+			return nil, apiError("ValidationError", "Resource [%s] is not in the stack", id)
+		}
+	}
+
+	s.steps = f.continueRollbackSteps(s, params.ResourcesToSkip, aws.ToString(params.ClientRequestToken))
+
+	return &cloudformation.ContinueUpdateRollbackOutput{}, nil
+}
+
+// SetStackPolicy stores the policy, it isn't enforced
+func (f *CloudFormation) SetStackPolicy(_ context.Context, params *cloudformation.SetStackPolicyInput, _ ...func(*cloudformation.Options)) (*cloudformation.SetStackPolicyOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls["SetStackPolicy"]++
+
+	s := f.lookup(aws.ToString(params.StackName))
+	if s == nil {
+		return nil, notExist(aws.ToString(params.StackName))
+	}
+	s.policy = aws.ToString(params.StackPolicyBody)
+
+	return &cloudformation.SetStackPolicyOutput{}, nil
+}
+
+// StackPolicy returns the policy set on the stack
+func (f *CloudFormation) StackPolicy(name string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if s := f.lookup(name); s != nil {
+		return s.policy
+	}
+	return ""
+}
+
+// ValidateTemplate parses the template and returns its parameters and the capabilities it needs
+func (f *CloudFormation) ValidateTemplate(_ context.Context, params *cloudformation.ValidateTemplateInput, _ ...func(*cloudformation.Options)) (*cloudformation.ValidateTemplateOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls["ValidateTemplate"]++
+
+	parsed, err := parseTemplate(aws.ToString(params.TemplateBody))
+	if err != nil {
+		return nil, err
+	}
+
+	output := &cloudformation.ValidateTemplateOutput{
+		Parameters:   parsed.parameters,
+		Capabilities: parsed.capabilities,
+	}
+	if len(parsed.capabilities) > 0 {
+		output.CapabilitiesReason = aws.String("The following resource(s) require capabilities: [" + strings.Join(parsed.iamResources, ", ") + "]")
+	}
+
+	return output, nil
+}
+
+// lookup finds a live stack by name or any stack by ID
+func (f *CloudFormation) lookup(nameOrID string) *stack {
+	if s, ok := f.stacks[nameOrID]; ok {
+		return s
+	}
+	return f.byID[nameOrID]
+}
+
+func (f *CloudFormation) newStack(name string) *stack {
+	f.ids++
+	s := &stack{
+		id:        fmt.Sprintf("arn:aws:cloudformation:us-west-2:123456789012:stack/%s/%08d-fake", name, f.ids),
+		name:      name,
+		resources: map[string]resource{},
+		created:   f.Now(),
+	}
+	f.stacks[name] = s
+	f.byID[s.id] = s
+	return s
+}
+
+func (f *CloudFormation) physicalID(stackName, logicalID string) string {
+	f.ids++
+	return fmt.Sprintf("%s-%s-%d", stackName, strings.ToLower(logicalID), f.ids)
+}
+
+func (f *CloudFormation) eventID() string {
+	f.ids++
+	return fmt.Sprintf("event-%d", f.ids)
+}
+
+// advance applies the next step of the stack's operation
+func (f *CloudFormation) advance(s *stack) {
+	if len(s.steps) == 0 {
+		return
+	}
+
+	next := s.steps[0]
+	s.steps = s.steps[1:]
+	f.record(s, next)
+
+	if next.done != nil {
+		next.done()
+	}
+}
+
+// record adds the event for the step and updates the stack status when it's about the stack
+func (f *CloudFormation) record(s *stack, st step) {
+	event := types.StackEvent{
+		EventId:            aws.String(f.eventID()),
+		StackId:            aws.String(s.id),
+		StackName:          aws.String(s.name),
+		LogicalResourceId:  aws.String(st.logicalID),
+		PhysicalResourceId: aws.String(st.physicalID),
+		ResourceType:       aws.String(st.resourceType),
+		ResourceStatus:     types.ResourceStatus(st.status),
+		Timestamp:          aws.Time(f.Now()),
+	}
+	if st.reason != "" {
+		event.ResourceStatusReason = aws.String(st.reason)
+	}
+	if st.token != "" {
+		event.ClientRequestToken = aws.String(st.token)
+	}
+	s.events = append(s.events, event)
+
+	if st.resourceType == stackResourceType && st.physicalID == s.id {
+		s.status = types.StackStatus(st.status)
+		s.reason = st.reason
+	}
+}
+
+func notExist(name string) error {
+	return apiError("ValidationError", "Stack with id %s does not exist", name)
+}
+
+func apiError(code, format string, args ...interface{}) error {
+	return &smithy.GenericAPIError{Code: code, Message: fmt.Sprintf(format, args...), Fault: smithy.FaultClient}
+}
+
+// inProgress reports whether an operation is running on the stack. A stack in review only holds a changeset.
+func inProgress(status types.StackStatus) bool {
+	return strings.HasSuffix(string(status), "_IN_PROGRESS") && status != types.StackStatusReviewInProgress
+}
@@ -0,0 +1,385 @@
+package fake
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+)
+
+const stackResourceType = "AWS::CloudFormation::Stack"
+
+type stack struct {
+	id         string
+	name       string
+	status     types.StackStatus
+	reason     string
+	parameters []types.Parameter
+	tags       []types.Tag
+	resources  map[string]resource
+	outputs    []types.Output
+	policy     string
+	events     []types.StackEvent
+	created    time.Time
+	// steps are the events still to come in the current operation
+	steps []step
+}
+
+type resource struct {
+	Type       string
+	Definition string
+	PhysicalID string
+}
+
+// step is one event of an operation, done runs once the event has been recorded
+type step struct {
+	logicalID    string
+	physicalID   string
+	resourceType string
+	status       string
+	reason       string
+	token        string
+	done         func()
+}
+
+func (s *stack) describe() types.Stack {
+	described := types.Stack{
+		StackId:      aws.String(s.id),
+		StackName:    aws.String(s.name),
+		StackStatus:  s.status,
+		Parameters:   append([]types.Parameter{}, s.parameters...),
+		Outputs:      append([]types.Output{}, s.outputs...),
+		Tags:         append([]types.Tag{}, s.tags...),
+		CreationTime: aws.Time(s.created),
+	}
+	if s.reason != "" {
+		described.StackStatusReason = aws.String(s.reason)
+	}
+	return described
+}
+
+func (s *stack) stackStep(status types.StackStatus, reason, token string) step {
+	return step{logicalID: s.name, physicalID: s.id, resourceType: stackResourceType, status: string(status), reason: reason, token: token}
+}
+
+func resourceStep(logicalID string, res resource, status, reason, token string) step {
+	return step{logicalID: logicalID, physicalID: res.PhysicalID, resourceType: res.Type, status: status, reason: reason, token: token}
+}
+
+// sortedIDs returns the logical IDs of the resources in a stable order
+func sortedIDs(resources map[string]resource) []string {
+	ids := make([]string, 0, len(resources))
+	for id := range resources {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// executeSteps plans the events of executing the changeset. Resources are changed one at a time in logical ID
+// order, a resource set to fail stops the operation and rolls it back.
+func (f *CloudFormation) executeSteps(s *stack, cs *changeSet, token string) []step {
+	creating := s.status == types.StackStatusReviewInProgress
+	verb := "UPDATE"
+	if creating {
+		verb = "CREATE"
+	}
+
+	previous := s.resources
+	next := map[string]resource{}
+	for id, res := range cs.resources {
+		res.PhysicalID = previous[id].PhysicalID
+		if res.PhysicalID == "" {
+			res.PhysicalID = f.physicalID(s.name, id)
+		}
+		next[id] = res
+	}
+
+	steps := []step{s.stackStep(types.StackStatus(verb+"_IN_PROGRESS"), "User Initiated", token)}
+	changed := []string{}
+	removed := []string{}
+
+	for _, change := range cs.changes {
+		id := aws.ToString(change.ResourceChange.LogicalResourceId)
+
+		action := "UPDATE"
+		switch change.ResourceChange.Action {
+		case types.ChangeActionRemove:
+			removed = append(removed, id)
+			continue
+		case types.ChangeActionAdd:
+			action = "CREATE"
+		}
+
+		steps = append(steps, resourceStep(id, next[id], action+"_IN_PROGRESS", "", token))
+
+		if reason, ok := f.failures[id]; ok {
+			delete(f.failures, id)
+			steps = append(steps, resourceStep(id, next[id], action+"_FAILED", reason, token))
+			return append(steps, f.rollbackSteps(s, cs, creating, id, append(changed, id), next, token)...)
+		}
+
+		steps = append(steps, resourceStep(id, next[id], action+"_COMPLETE", "", token))
+		changed = append(changed, id)
+	}
+
+	complete := func() {
+		s.resources = next
+		s.parameters = cs.parameters
+		s.tags = cs.tags
+		if outputs, ok := f.outputs[s.name]; ok {
+			s.outputs = outputs
+		}
+		cs.executionStatus = types.ExecutionStatusExecuteComplete
+	}
+
+	if creating {
+		last := s.stackStep(types.StackStatusCreateComplete, "", token)
+		last.done = complete
+		return append(steps, last)
+	}
+
+	steps = append(steps, s.stackStep(types.StackStatusUpdateCompleteCleanupInProgress, "", token))
+	for _, id := range removed {
+		steps = append(steps,
+			resourceStep(id, previous[id], "DELETE_IN_PROGRESS", "", token),
+			resourceStep(id, previous[id], "DELETE_COMPLETE", "", token))
+	}
+
+	last := s.stackStep(types.StackStatusUpdateComplete, "", token)
+	last.done = complete
+
+	return append(steps, last)
+}
+
+// rollbackSteps plans the events of rolling back a failed create or update. A failed create deletes what it
+// created, a failed update puts the changed resources back and deletes the ones it added.
+func (f *CloudFormation) rollbackSteps(s *stack, cs *changeSet, creating bool, failed string, changed []string, next map[string]resource, token string) []step {
+	failRollback := f.failRollback
+	f.failRollback = false
+
+	previous := s.resources
+	finish := func(resources map[string]resource) func() {
+		return func() {
+			s.resources = resources
+			cs.executionStatus = types.ExecutionStatusExecuteFailed
+		}
+	}
+
+	if creating {
+		steps := []step{s.stackStep(types.StackStatusRollbackInProgress,
+			fmt.Sprintf("The following resource(s) failed to create: [%s]. Rollback requested by user.", failed), token)}
+
+		for i := len(changed) - 1; i >= 0; i-- {
+			id := changed[i]
+			steps = append(steps, resourceStep(id, next[id], "DELETE_IN_PROGRESS", "", token))
+			if failRollback {
+				steps = append(steps, resourceStep(id, next[id], "DELETE_FAILED", "Resource could not be deleted", token))
+				last := s.stackStep(types.StackStatusRollbackFailed, "The following resource(s) failed to delete: ["+id+"].", token)
+				last.done = finish(map[string]resource{id: next[id]})
+				return append(steps, last)
+			}
+			steps = append(steps, resourceStep(id, next[id], "DELETE_COMPLETE", "", token))
+		}
+
+		last := s.stackStep(types.StackStatusRollbackComplete, "", token)
+		last.done = finish(map[string]resource{})
+		return append(steps, last)
+	}
+
+	steps := []step{s.stackStep(types.StackStatusUpdateRollbackInProgress,
+		fmt.Sprintf("The following resource(s) failed to update: [%s].", failed), token)}
+	added := []string{}
+
+	for i := len(changed) - 1; i >= 0; i-- {
+		id := changed[i]
+		old, existed := previous[id]
+		if !existed {
+			added = append(added, id)
+			continue
+		}
+
+		steps = append(steps, resourceStep(id, old, "UPDATE_IN_PROGRESS", "", token))
+		if failRollback {
+			steps = append(steps, resourceStep(id, old, "UPDATE_FAILED", "Resource could not be restored", token))
+			last := s.stackStep(types.StackStatusUpdateRollbackFailed, "The following resource(s) failed to update: ["+id+"].", token)
+			last.done = finish(previous)
+			return append(steps, last)
+		}
+		steps = append(steps, resourceStep(id, old, "UPDATE_COMPLETE", "", token))
+	}
+
+	steps = append(steps, s.stackStep(types.StackStatusUpdateRollbackCompleteCleanupInProgress, "", token))
+	for _, id := range added {
+		steps = append(steps,
+			resourceStep(id, next[id], "DELETE_IN_PROGRESS", "", token),
+			resourceStep(id, next[id], "DELETE_COMPLETE", "", token))
+	}
+
+	last := s.stackStep(types.StackStatusUpdateRollbackComplete, "", token)
+	last.done = finish(previous)
+
+	return append(steps, last)
+}
+
+// continueRollbackSteps plans the events of continuing a failed update rollback, skipped resources are left as
+// they are
+func (f *CloudFormation) continueRollbackSteps(s *stack, skip []string, token string) []step {
+	steps := []step{s.stackStep(types.StackStatusUpdateRollbackInProgress, "User Initiated", token)}
+
+	skipped := map[string]bool{}
+	for _, id := range skip {
+		skipped[id] = true
+		steps = append(steps, resourceStep(id, s.resources[id], "UPDATE_COMPLETE", "Resource skipped during UPDATE_ROLLBACK", token))
+	}
+
+	for _, id := range sortedIDs(s.resources) {
+		reason, ok := f.failures[id]
+		if !ok || skipped[id] {
+			continue
+		}
+		delete(f.failures, id)
+
+		return append(steps,
+			resourceStep(id, s.resources[id], "UPDATE_IN_PROGRESS", "", token),
+			resourceStep(id, s.resources[id], "UPDATE_FAILED", reason, token),
+			s.stackStep(types.StackStatusUpdateRollbackFailed, "The following resource(s) failed to update: ["+id+"].", token))
+	}
+
+	steps = append(steps, s.stackStep(types.StackStatusUpdateRollbackCompleteCleanupInProgress, "", token))
+	for _, id := range sortedIDs(s.resources) {
+		if reason, ok := f.cleanupFailures[id]; ok {
+			delete(f.cleanupFailures, id)
+			steps = append(steps, resourceStep(id, s.resources[id], "DELETE_FAILED", reason, token))
+		}
+	}
+
+	return append(steps, s.stackStep(types.StackStatusUpdateRollbackComplete, "", token))
+}
+
+// deleteSteps plans the events of deleting the stack, resources are deleted in reverse logical ID order
+func (f *CloudFormation) deleteSteps(s *stack, token string) []step {
+	steps := []step{s.stackStep(types.StackStatusDeleteInProgress, "User Initiated", token)}
+
+	ids := sortedIDs(s.resources)
+	for i := len(ids) - 1; i >= 0; i-- {
+		id := ids[i]
+		res := s.resources[id]
+		steps = append(steps, resourceStep(id, res, "DELETE_IN_PROGRESS", "", token))
+
+		if reason, ok := f.failures[id]; ok {
+			delete(f.failures, id)
+			steps = append(steps, resourceStep(id, res, "DELETE_FAILED", reason, token))
+
+			remaining := map[string]resource{}
+			for _, kept := range ids[:i+1] {
+				remaining[kept] = s.resources[kept]
+			}
+
+			last := s.stackStep(types.StackStatusDeleteFailed, "The following resource(s) failed to delete: ["+id+"].", token)
+			last.done = func() { s.resources = remaining }
+			return append(steps, last)
+		}
+
+		steps = append(steps, resourceStep(id, res, "DELETE_COMPLETE", "", token))
+	}
+
+	last := s.stackStep(types.StackStatusDeleteComplete, "", token)
+	last.done = func() {
+		s.resources = map[string]resource{}
+		delete(f.stacks, s.name)
+		for _, cs := range f.changeSets {
+			if cs.stackID == s.id {
+				delete(f.changeSets, cs.id)
+			}
+		}
+	}
+
+	return append(steps, last)
+}
+
+// updatable reports whether a changeset can update a stack in the status
+func updatable(status types.StackStatus) bool {
+	switch status {
+	case types.StackStatusCreateComplete,
+		types.StackStatusUpdateComplete,
+		types.StackStatusUpdateRollbackComplete,
+		types.StackStatusImportComplete,
+		types.StackStatusImportRollbackComplete:
+		return true
+	}
+	return false
+}
+
+// changedParameters returns the names of parameters whose value differs between the two sets
+func changedParameters(before, after []types.Parameter) []string {
+	old := map[string]string{}
+	for _, parameter := range before {
+		old[aws.ToString(parameter.ParameterKey)] = aws.ToString(parameter.ParameterValue)
+	}
+
+	changed := []string{}
+	for _, parameter := range after {
+		key := aws.ToString(parameter.ParameterKey)
+		if value, ok := old[key]; !ok || value != aws.ToString(parameter.ParameterValue) {
+			changed = append(changed, key)
+		}
+	}
+	return changed
+}
+
+// diffResources returns the changes from the current resources to the desired ones. A resource is modified
+// when its definition changes or it refers to a parameter that changed.
+func diffResources(current, desired map[string]resource, parameters []string) []types.Change {
+	changes := []types.Change{}
+
+	for _, id := range sortedIDs(desired) {
+		res := desired[id]
+		old, ok := current[id]
+
+		var rc *types.ResourceChange
+		switch {
+		case !ok:
+			rc = &types.ResourceChange{Action: types.ChangeActionAdd}
+		case old.Definition != res.Definition || refersTo(res.Definition, parameters):
+			rc = &types.ResourceChange{
+				Action:             types.ChangeActionModify,
+				PhysicalResourceId: aws.String(old.PhysicalID),
+				Replacement:        types.ReplacementFalse,
+				Scope:              []types.ResourceAttribute{types.ResourceAttributeProperties},
+			}
+		default:
+			continue
+		}
+
+		rc.LogicalResourceId, rc.ResourceType = aws.String(id), aws.String(res.Type)
+		changes = append(changes, types.Change{Type: types.ChangeTypeResource, ResourceChange: rc})
+	}
+
+	for _, id := range sortedIDs(current) {
+		if _, ok := desired[id]; ok {
+			continue
+		}
+		changes = append(changes, types.Change{Type: types.ChangeTypeResource, ResourceChange: &types.ResourceChange{
+			Action:             types.ChangeActionRemove,
+			LogicalResourceId:  aws.String(id),
+			PhysicalResourceId: aws.String(current[id].PhysicalID),
+			ResourceType:       aws.String(current[id].Type),
+		}})
+	}
+
+	return changes
+}
+
+// refersTo reports whether the definition refers to one of the parameters with Ref or ${} in a Sub
+func refersTo(definition string, parameters []string) bool {
+	for _, parameter := range parameters {
+		if regexp.MustCompile(`(?:Ref:?\s+"?|\$\{)` + regexp.QuoteMeta(parameter) + `\b`).MatchString(definition) {
+			return true
+		}
+	}
+	return false
+}
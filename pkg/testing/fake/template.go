@@ -0,0 +1,114 @@
+package fake
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"gopkg.in/yaml.v3"
+)
+
+type parsedTemplate struct {
+	parameters   []types.TemplateParameter
+	resources    map[string]resource
+	capabilities []types.Capability
+	iamResources []string
+}
+
+// parseTemplate reads the parameters and resources of a JSON or YAML template
+func parseTemplate(body string) (*parsedTemplate, error) {
+	root := &yaml.Node{}
+	if err := yaml.Unmarshal([]byte(body), root); err != nil {
+		return nil, apiError("ValidationError", "Template format error: %s", err)
+	}
+
+	if len(root.Content) == 0 || root.Content[0].Kind != yaml.MappingNode {
+		return nil, apiError("ValidationError", "Template format error: template must be a map")
+	}
+
+	parsed := &parsedTemplate{resources: map[string]resource{}}
+
+	resources := mappingValue(root.Content[0], "Resources")
+	if resources == nil || resources.Kind != yaml.MappingNode || len(resources.Content) == 0 {
+		return nil, apiError("ValidationError", "Template format error: At least one Resources member must be defined.")
+	}
+
+	for i := 0; i+1 < len(resources.Content); i += 2 {
+		id, definition := resources.Content[i].Value, resources.Content[i+1]
+
+		resourceType := mappingValue(definition, "Type")
+		if resourceType == nil || resourceType.Value == "" {
+			return nil, apiError("ValidationError", "Template format error: [/Resources/%s] Every Resources object must contain a Type member.", id)
+		}
+
+		encoded, err := yaml.Marshal(definition)
+		if err != nil {
+			return nil, apiError("ValidationError", "Template format error: %s", err)
+		}
+
+		parsed.resources[id] = resource{Type: resourceType.Value, Definition: string(encoded)}
+
+		if strings.HasPrefix(resourceType.Value, "AWS::IAM::") {
+			parsed.iamResources = append(parsed.iamResources, id)
+		}
+	}
+
+	if len(parsed.iamResources) > 0 {
+		sort.Strings(parsed.iamResources)
+		parsed.capabilities = []types.Capability{types.CapabilityCapabilityIam}
+	}
+
+	if parameters := mappingValue(root.Content[0], "Parameters"); parameters != nil && parameters.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(parameters.Content); i += 2 {
+			parsed.parameters = append(parsed.parameters, templateParameter(parameters.Content[i].Value, parameters.Content[i+1]))
+		}
+	}
+
+	return parsed, nil
+}
+
+func templateParameter(name string, node *yaml.Node) types.TemplateParameter {
+	parameter := types.TemplateParameter{ParameterKey: aws.String(name)}
+
+	if value := mappingValue(node, "Default"); value != nil {
+		parameter.DefaultValue = aws.String(value.Value)
+	}
+	if value := mappingValue(node, "Description"); value != nil {
+		parameter.Description = aws.String(value.Value)
+	}
+	if value := mappingValue(node, "NoEcho"); value != nil {
+		parameter.NoEcho = aws.Bool(value.Value == "true")
+	}
+
+	return parameter
+}
+
+// missingCapabilities returns the capability the template needs that wasn't acknowledged, empty when none are
+// missing. CAPABILITY_NAMED_IAM covers CAPABILITY_IAM.
+func missingCapabilities(needed, acknowledged []types.Capability) string {
+	for _, capability := range needed {
+		ok := false
+		for _, ack := range acknowledged {
+			if ack == capability || ack == types.CapabilityCapabilityNamedIam {
+				ok = true
+			}
+		}
+		if !ok {
+			return string(capability)
+		}
+	}
+	return ""
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
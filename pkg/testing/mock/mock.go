@@ -8,17 +8,23 @@ import (
 
 	awsmiddle "github.com/aws/aws-sdk-go-v2/aws/middleware"
 	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
 	"github.com/aws/smithy-go/middleware"
 )
 
 type CloudFormationMock struct {
-	callCount                      map[string]int
-	createChangeSetMockReturns     CreateChangeSetReturns
-	deleteStackMockReturns         DeleteStackReturns
-	describeChangeSetMockReturns   DescribeChangeSetReturns
-	describeStackEventsMockReturns DescribeStackEventsReturns
-	describeStacksMockReturns      DescribeStacksReturns
-	executeChangeSetMockReturns    ExecuteChangeSetReturns
+	callCount                         map[string]int
+	continueUpdateRollbackMockReturns ContinueUpdateRollbackReturns
+	createChangeSetMockReturns        CreateChangeSetReturns
+	deleteChangeSetMockReturns        DeleteChangeSetReturns
+	deleteStackMockReturns            DeleteStackReturns
+	describeChangeSetMockReturns      DescribeChangeSetReturns
+	describeStackEventsMockReturns    DescribeStackEventsReturns
+	describeStacksMockReturns         DescribeStacksReturns
+	executeChangeSetMockReturns       ExecuteChangeSetReturns
+	listChangeSetsMockReturns         ListChangeSetsReturns
+	setStackPolicyMockReturns         SetStackPolicyReturns
+	validateTemplateMockReturns       ValidateTemplateReturns
 }
 
 func NewCloudFormationMock() *CloudFormationMock {
@@ -31,6 +37,19 @@ func (c *CloudFormationMock) GetCallCount() map[string]int {
 	return c.callCount
 }
 
+type ContinueUpdateRollbackReturns struct {
+	Return cloudformation.ContinueUpdateRollbackOutput
+	Error  error
+}
+
+func (c *CloudFormationMock) SetContinueUpdateRollbackReturn(o cloudformation.ContinueUpdateRollbackOutput) {
+	c.continueUpdateRollbackMockReturns.Return = o
+}
+
+func (c *CloudFormationMock) SetContinueUpdateRollbackError(e error) {
+	c.continueUpdateRollbackMockReturns.Error = e
+}
+
 type CreateChangeSetReturns struct {
 	Return cloudformation.CreateChangeSetOutput
 	Error  error
@@ -44,6 +63,19 @@ func (c *CloudFormationMock) SetCreateChangeSetError(e error) {
 	c.createChangeSetMockReturns.Error = e
 }
 
+type DeleteChangeSetReturns struct {
+	Return cloudformation.DeleteChangeSetOutput
+	Error  error
+}
+
+func (c *CloudFormationMock) SetDeleteChangeSetReturn(o cloudformation.DeleteChangeSetOutput) {
+	c.deleteChangeSetMockReturns.Return = o
+}
+
+func (c *CloudFormationMock) SetDeleteChangeSetError(e error) {
+	c.deleteChangeSetMockReturns.Error = e
+}
+
 type DeleteStackReturns struct {
 	Return cloudformation.DeleteStackOutput
 	Error  error
@@ -109,6 +141,45 @@ func (c *CloudFormationMock) SetExecuteChangeSetError(e error) {
 	c.executeChangeSetMockReturns.Error = e
 }
 
+type ListChangeSetsReturns struct {
+	Return cloudformation.ListChangeSetsOutput
+	Error  error
+}
+
+func (c *CloudFormationMock) SetListChangeSetsReturn(o cloudformation.ListChangeSetsOutput) {
+	c.listChangeSetsMockReturns.Return = o
+}
+
+func (c *CloudFormationMock) SetListChangeSetsError(e error) {
+	c.listChangeSetsMockReturns.Error = e
+}
+
+type SetStackPolicyReturns struct {
+	Return cloudformation.SetStackPolicyOutput
+	Error  error
+}
+
+func (c *CloudFormationMock) SetSetStackPolicyReturn(o cloudformation.SetStackPolicyOutput) {
+	c.setStackPolicyMockReturns.Return = o
+}
+
+func (c *CloudFormationMock) SetSetStackPolicyError(e error) {
+	c.setStackPolicyMockReturns.Error = e
+}
+
+type ValidateTemplateReturns struct {
+	Return cloudformation.ValidateTemplateOutput
+	Error  error
+}
+
+func (c *CloudFormationMock) SetValidateTemplateReturn(o cloudformation.ValidateTemplateOutput) {
+	c.validateTemplateMockReturns.Return = o
+}
+
+func (c *CloudFormationMock) SetValidateTemplateError(e error) {
+	c.validateTemplateMockReturns.Error = e
+}
+
 func (c *CloudFormationMock) CloudFormationMiddlewareInjector() func(stack *middleware.Stack) error {
 	return func(stack *middleware.Stack) error {
 		return stack.Finalize.Add(
@@ -116,11 +187,21 @@ func (c *CloudFormationMock) CloudFormationMiddlewareInjector() func(stack *midd
 				"CloudFormationMiddleware",
 				func(ctx context.Context, input middleware.FinalizeInput, handler middleware.FinalizeHandler) (middleware.FinalizeOutput, middleware.Metadata, error) {
 					switch awsmiddle.GetOperationName(ctx) {
+					case "ContinueUpdateRollback":
+						c.callCount["ContinueUpdateRollback"] += 1
+						return middleware.FinalizeOutput{
+							Result: &c.continueUpdateRollbackMockReturns.Return,
+						}, middleware.Metadata{}, c.continueUpdateRollbackMockReturns.Error
 					case "CreateChangeSet":
 						c.callCount["CreateChangeSet"] += 1
 						return middleware.FinalizeOutput{
 							Result: &c.createChangeSetMockReturns.Return,
 						}, middleware.Metadata{}, c.createChangeSetMockReturns.Error
+					case "DeleteChangeSet":
+						c.callCount["DeleteChangeSet"] += 1
+						return middleware.FinalizeOutput{
+							Result: &c.deleteChangeSetMockReturns.Return,
+						}, middleware.Metadata{}, c.deleteChangeSetMockReturns.Error
 					case "DeleteStack":
 						c.callCount["DeleteStack"] += 1
 						return middleware.FinalizeOutput{
@@ -146,6 +227,72 @@ func (c *CloudFormationMock) CloudFormationMiddlewareInjector() func(stack *midd
 						return middleware.FinalizeOutput{
 							Result: &c.executeChangeSetMockReturns.Return,
 						}, middleware.Metadata{}, c.executeChangeSetMockReturns.Error
+					case "ListChangeSets":
+						c.callCount["ListChangeSets"] += 1
+						return middleware.FinalizeOutput{
+							Result: &c.listChangeSetsMockReturns.Return,
+						}, middleware.Metadata{}, c.listChangeSetsMockReturns.Error
+					case "SetStackPolicy":
+						c.callCount["SetStackPolicy"] += 1
+						return middleware.FinalizeOutput{
+							Result: &c.setStackPolicyMockReturns.Return,
+						}, middleware.Metadata{}, c.setStackPolicyMockReturns.Error
+					case "ValidateTemplate":
+						c.callCount["ValidateTemplate"] += 1
+						return middleware.FinalizeOutput{
+							Result: &c.validateTemplateMockReturns.Return,
+						}, middleware.Metadata{}, c.validateTemplateMockReturns.Error
+					default:
+						panic(fmt.Sprintf("Operation is not mocked %s", awsmiddle.GetOperationName(ctx)))
+					}
+
+				},
+			),
+			middleware.Before,
+		)
+	}
+}
+
+type TypesMock struct {
+	callCount         map[string]int
+	valuesMockReturns ValuesReturns
+}
+
+func NewTypesMock() *TypesMock {
+	return &TypesMock{
+		callCount: make(map[string]int),
+	}
+}
+
+func (t *TypesMock) GetCallCount() map[string]int {
+	return t.callCount
+}
+
+type ValuesReturns struct {
+	Return types.Capability
+	Error  error
+}
+
+func (t *TypesMock) SetValuesReturn(o types.Capability) {
+	t.valuesMockReturns.Return = o
+}
+
+func (t *TypesMock) SetValuesError(e error) {
+	t.valuesMockReturns.Error = e
+}
+
+func (t *TypesMock) TypesMiddlewareInjector() func(stack *middleware.Stack) error {
+	return func(stack *middleware.Stack) error {
+		return stack.Finalize.Add(
+			middleware.FinalizeMiddlewareFunc(
+				"TypesMiddleware",
+				func(ctx context.Context, input middleware.FinalizeInput, handler middleware.FinalizeHandler) (middleware.FinalizeOutput, middleware.Metadata, error) {
+					switch awsmiddle.GetOperationName(ctx) {
+					case "Values":
+						t.callCount["Values"] += 1
+						return middleware.FinalizeOutput{
+							Result: &t.valuesMockReturns.Return,
+						}, middleware.Metadata{}, t.valuesMockReturns.Error
 					default:
 						panic(fmt.Sprintf("Operation is not mocked %s", awsmiddle.GetOperationName(ctx)))
 					}
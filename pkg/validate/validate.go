@@ -0,0 +1,138 @@
+package validate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/massdriver-cloud/fogmachine/pkg/client"
+	"github.com/massdriver-cloud/fogmachine/pkg/template"
+)
+
+// Options configure Validate
+type Options struct {
+	TemplatePath string
+	// Remote also validates the template with the CloudFormation ValidateTemplate API, once it has no errors
+	Remote bool
+	Region string
+	// CloudFormation is the API client Remote uses, one is created for Region from the default AWS config when it's nil
+	CloudFormation client.CloudFormationAPI
+}
+
+// Result is what validating the template found
+type Result struct {
+	Problems []template.Problem
+	// Remote is the ValidateTemplate response, nil unless the template was validated remotely
+	Remote *cloudformation.ValidateTemplateOutput
+}
+
+// Validate lints the template and, when the options ask for it, validates it with CloudFormation. It returns the
+// result so far along with any error, a template with problems of error severity is an error.
+func Validate(ctx context.Context, opts Options) (*Result, error) {
+	body, err := os.ReadFile(opts.TemplatePath)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := template.Parse(opts.TemplatePath, body)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{Problems: doc.Lint()}
+
+	if n := errorCount(result.Problems); n > 0 {
+		return result, fmt.Errorf("template is invalid, %d problems are errors", n)
+	}
+
+	if !opts.Remote {
+		return result, nil
+	}
+
+	if opts.Region == "" && opts.CloudFormation == nil {
+		return result, errors.New("--region is required with --remote")
+	}
+
+	// Validating doesn't touch a stack so the client needs no stack name
+	cf, err := client.New(ctx, client.Config{Region: opts.Region, CloudFormation: opts.CloudFormation})
+	if err != nil {
+		return result, err
+	}
+
+	result.Remote, err = cf.ValidateTemplate(ctx, body)
+
+	return result, err
+}
+
+// Write prints the problems and, when the template was validated remotely, the capabilities and parameters it needs
+func (r *Result) Write(w io.Writer) error {
+	writeProblems(w, r.Problems)
+
+	if r.Remote == nil {
+		return nil
+	}
+
+	return writeRemote(w, r.Remote)
+}
+
+func errorCount(problems []template.Problem) int {
+	count := 0
+	for _, problem := range problems {
+		if problem.Severity == template.SeverityError {
+			count++
+		}
+	}
+	return count
+}
+
+func writeProblems(w io.Writer, problems []template.Problem) {
+	if len(problems) == 0 {
+		fmt.Fprintln(w, "No problems found")
+		return
+	}
+
+	for _, problem := range problems {
+		fmt.Fprintln(w, problem.String())
+	}
+}
+
+func writeRemote(w io.Writer, result *cloudformation.ValidateTemplateOutput) error {
+	capabilities := make([]string, 0, len(result.Capabilities))
+	for _, capability := range result.Capabilities {
+		capabilities = append(capabilities, string(capability))
+	}
+
+	if len(capabilities) == 0 {
+		fmt.Fprintln(w, "Required capabilities: none")
+	} else {
+		fmt.Fprintf(w, "Required capabilities: %s\n", strings.Join(capabilities, ", "))
+		if result.CapabilitiesReason != nil {
+			fmt.Fprintf(w, "  %s\n", *result.CapabilitiesReason)
+		}
+	}
+
+	if len(result.Parameters) == 0 {
+		fmt.Fprintln(w, "Parameters: none")
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PARAMETER\tDEFAULT\tNO ECHO\tDESCRIPTION")
+
+	for _, parameter := range result.Parameters {
+		fmt.Fprintf(tw, "%s\t%s\t%t\t%s\n",
+			aws.ToString(parameter.ParameterKey),
+			aws.ToString(parameter.DefaultValue),
+			aws.ToBool(parameter.NoEcho),
+			aws.ToString(parameter.Description),
+		)
+	}
+
+	return tw.Flush()
+}
@@ -0,0 +1,31 @@
+package validate_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/massdriver-cloud/fogmachine/pkg/testing/fake"
+	"github.com/massdriver-cloud/fogmachine/pkg/validate"
+)
+
+func TestValidate(t *testing.T) {
+	cf := fake.New()
+
+	result, err := validate.Validate(context.Background(), validate.Options{TemplatePath: "../template/testdata/lint.yaml", Remote: true, CloudFormation: cf})
+	if err == nil || len(result.Problems) == 0 {
+		t.Fatalf("Got %v with %d problems but expected the lint errors", err, len(result.Problems))
+	}
+
+	if result.Remote != nil || cf.Calls("ValidateTemplate") != 0 {
+		t.Fatalf("Got %d remote validations but expected none for a template with errors", cf.Calls("ValidateTemplate"))
+	}
+
+	result, err = validate.Validate(context.Background(), validate.Options{TemplatePath: "../template/testdata/s3.yaml", Remote: true, CloudFormation: cf})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Remote == nil || len(result.Remote.Parameters) != 4 {
+		t.Fatalf("Got %+v but expected the remote validation with 4 parameters", result.Remote)
+	}
+}